@@ -1,7 +1,7 @@
 // This is my implementation of AES-128
 // It of course requires the implemntation of SubBytes, ShiftRows, MixColumns, AddRoundKey and their inverses
 // It also requires the key expansion function which was my motivation for sticking to 128 bits
-package main
+package aes
 
 import (
 	"errors"
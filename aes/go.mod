@@ -0,0 +1,3 @@
+module github.com/Alextopher/crypto/aes
+
+go 1.17
@@ -0,0 +1,107 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// Fingerprint is the SHA-256 hash of an encoded public key
+type Fingerprint [sha256.Size]byte
+
+// Of hashes the encoding of a public key, keys with the same encoding have the same fingerprint
+func Of(encoded []byte) Fingerprint {
+	return sha256.Sum256(encoded)
+}
+
+// String formats the fingerprint like ssh-keygen -l: SHA256: followed by unpadded base64
+func (f Fingerprint) String() string {
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(f[:])
+}
+
+// The size of the randomart field
+const (
+	width  = 17
+	height = 9
+)
+
+// Symbols for how often the bishop visited a square, the last two mark where it started and ended
+const symbols = " .o+=*BOX@%&#/^SE"
+
+// Randomart draws the fingerprint with OpenSSH's drunken bishop, the title goes in the top border.
+// Every byte moves the bishop four times, two bits at a time starting with the lowest:
+// the first bit moves it left or right and the second up or down, it stays inside the field.
+func (f Fingerprint) Randomart(title string) string {
+	var field [width][height]int
+	x, y := width/2, height/2
+	start := x + y*width
+
+	// The busiest squares saturate below the start and end symbols
+	most := len(symbols) - 3
+
+	for _, b := range f {
+		for i := 0; i < 4; i++ {
+			if b&1 != 0 {
+				x++
+			} else {
+				x--
+			}
+
+			if b&2 != 0 {
+				y++
+			} else {
+				y--
+			}
+
+			x = clamp(x, width-1)
+			y = clamp(y, height-1)
+
+			if field[x][y] < most {
+				field[x][y]++
+			}
+			b >>= 2
+		}
+	}
+
+	var art strings.Builder
+	art.WriteString(border(title))
+	for row := 0; row < height; row++ {
+		art.WriteByte('|')
+		for col := 0; col < width; col++ {
+			switch {
+			case col+row*width == start:
+				art.WriteByte(symbols[len(symbols)-2])
+			case col == x && row == y:
+				art.WriteByte(symbols[len(symbols)-1])
+			default:
+				art.WriteByte(symbols[field[col][row]])
+			}
+		}
+		art.WriteString("|\n")
+	}
+	art.WriteString(border("SHA256"))
+
+	return art.String()
+}
+
+// Keeps n between 0 and max
+func clamp(n, max int) int {
+	if n < 0 {
+		return 0
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// A border line with the title in brackets near the middle, long titles are cut to fit
+func border(title string) string {
+	title = "[" + title + "]"
+	if len(title) > width {
+		title = title[:width-1] + "]"
+	}
+
+	left := (width - len(title)) / 2
+	return "+" + strings.Repeat("-", left) + title + strings.Repeat("-", width-left-len(title)) + "+\n"
+}
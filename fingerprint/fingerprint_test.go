@@ -0,0 +1,64 @@
+package fingerprint
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// An ed25519 key made by ssh-keygen, with the output of ssh-keygen -lv
+const (
+	sshKey         = "AAAAC3NzaC1lZDI1NTE5AAAAICohxbtiKe066UI4QD/RVHeP/bgxzmywG9/91O6vNzHw"
+	sshFingerprint = "SHA256:09w+MNtrpBJISqAmoljcVHPlPQ759lOFV5n3CJWLhUo"
+	sshRandomart   = `+--[ED25519 256]--+
+|     .o ...  o..+|
+|  . .  o .Eoo o+o|
+| o +     .+.o+.o=|
+|+.o o .  o.=..o.o|
+|*. . o .S = =   .|
+|o   . . .. B.. . |
+|         ..o+ o  |
+|        . . .o . |
+|         . ..    |
++----[SHA256]-----+
+`
+)
+
+// Matches ssh-keygen for the same key
+func TestOpenSSH(t *testing.T) {
+	blob, err := base64.StdEncoding.DecodeString(sshKey)
+	if err != nil {
+		t.Fatalf("Could not decode the key: %s", err)
+	}
+
+	f := Of(blob)
+	if f.String() != sshFingerprint {
+		t.Errorf("Fingerprint is %s, expected %s", f, sshFingerprint)
+	}
+
+	if art := f.Randomart("ED25519 256"); art != sshRandomart {
+		t.Errorf("Randomart does not match ssh-keygen\ngot:\n%s\nexpected:\n%s", art, sshRandomart)
+	}
+}
+
+func TestRandomartShape(t *testing.T) {
+	for _, title := range []string{"", "RSA 2048", "a title that is far too long for the border"} {
+		lines := strings.Split(strings.TrimSuffix(Of([]byte(title)).Randomart(title), "\n"), "\n")
+		if len(lines) != height+2 {
+			t.Errorf("%q: randomart has %d lines, expected %d", title, len(lines), height+2)
+		}
+
+		for _, line := range lines {
+			if len(line) != width+2 {
+				t.Errorf("%q: line %q is not %d characters wide", title, line, width+2)
+			}
+		}
+	}
+}
+
+func TestDifferentKeys(t *testing.T) {
+	a, b := Of([]byte("key a")), Of([]byte("key b"))
+	if a == b || a.String() == b.String() || a.Randomart("") == b.Randomart("") {
+		t.Errorf("Different keys have the same fingerprint")
+	}
+}
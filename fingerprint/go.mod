@@ -0,0 +1,3 @@
+module github.com/Alextopher/crypto/fingerprint
+
+go 1.17
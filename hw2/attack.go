@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	rsa "github.com/Alextopher/crypto/hw2/myrsa"
+)
+
+// Attack usage
+// rsa attack keys <public_key>...
+// rsa attack broadcast <plaintext> <public_key> <ciphertext> [<public_key> <ciphertext>...]
+// rsa attack common <plaintext> <public_key> <ciphertext> <public_key> <ciphertext>
+// rsa attack bytes <public_key> [<ciphertext> [<plaintext>]]
+var attackUsage = map[string]string{
+	"keys":      "<public_key>...",
+	"broadcast": "<plaintext> <public_key> <ciphertext> [<public_key> <ciphertext>...]",
+	"common":    "<plaintext> <public_key> <ciphertext> <public_key> <ciphertext>",
+	"bytes":     "<public_key> [<ciphertext> [<plaintext>]]",
+}
+
+func attack(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 1, math.MaxInt)
+	if err != nil {
+		return err
+	}
+
+	mode := args[0]
+	if _, ok := attackUsage[mode]; !ok {
+		return c.usageError(cmd)
+	}
+	usage := fail(exitUsage, "usage: rsa attack %s %s", mode, attackUsage[mode])
+	args = args[1:]
+
+	switch mode {
+	case "keys":
+		if len(args) < 1 {
+			return usage
+		}
+		return attackKeys(c, args)
+	case "broadcast", "common":
+		if len(args) < 3 || len(args)%2 != 1 || (mode == "common" && len(args) != 5) {
+			return usage
+		}
+		return attackMessage(c, mode, args)
+	default:
+		if len(args) < 1 || len(args) > 3 {
+			return usage
+		}
+		return attackBytes(c, args)
+	}
+}
+
+// Errors from an attack that didn't work end with exitFailed
+func attackError(err error) error {
+	if errors.As(err, &rsa.ErrAttackFailed{}) {
+		return fail(exitFailed, "%w", err)
+	}
+	return fail(exitCrypto, "%w", err)
+}
+
+// Tries each key on its own, then looks for shared primes, and writes every key it breaks to <public_key>.recovered
+func attackKeys(c *cli, names []string) error {
+	keys := make([]*rsa.PublicKey, len(names))
+	for i, name := range names {
+		public, err := c.loadPublicKey(name)
+		if err != nil {
+			return err
+		}
+		keys[i] = public
+	}
+
+	broken := make([]*rsa.PrivateKey, len(keys))
+	for i, public := range keys {
+		private, method, err := rsa.AttackKey(public)
+		if err != nil {
+			fmt.Fprintln(c.stdout, names[i]+":", err)
+			continue
+		}
+
+		fmt.Fprintln(c.stdout, names[i]+": broken by", method)
+		broken[i] = private
+	}
+
+	for i, private := range rsa.BatchGCD(keys) {
+		if private != nil && broken[i] == nil {
+			fmt.Fprintln(c.stdout, names[i]+": broken by batch GCD, it shares a prime with another key")
+			broken[i] = private
+		}
+	}
+
+	// Save every private key that was found
+	found := false
+	for i, private := range broken {
+		if private == nil {
+			continue
+		}
+		found = true
+
+		if err := c.writeFile(names[i]+".recovered", 0600, private.SavePEM); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fail(exitFailed, "no key was broken")
+	}
+	return nil
+}
+
+// Recovers a message sent to several keys, with Håstad's attack or the common modulus attack
+func attackMessage(c *cli, mode string, args []string) error {
+	var keys []*rsa.PublicKey
+	var ciphertexts []io.Reader
+	for i := 1; i < len(args); i += 2 {
+		public, err := c.loadPublicKey(args[i])
+		if err != nil {
+			return err
+		}
+
+		ciphertext, err := c.open(args[i+1])
+		if err != nil {
+			return err
+		}
+		defer ciphertext.Close()
+
+		keys = append(keys, public)
+		ciphertexts = append(ciphertexts, ciphertext)
+	}
+
+	return c.writeFile(args[0], 0644, func(w io.Writer) error {
+		var err error
+		if mode == "broadcast" {
+			err = rsa.HastadDecrypt(keys, ciphertexts, w)
+		} else {
+			err = rsa.CommonModulusDecrypt(keys[0], keys[1], ciphertexts[0], ciphertexts[1], w)
+		}
+
+		if err != nil {
+			return attackError(err)
+		}
+		return nil
+	})
+}
+
+// Decrypts a file encrypted one byte at a time with only the public key
+func attackBytes(c *cli, args []string) error {
+	public, err := c.loadPublicKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := c.open(argOrStdio(args, 1))
+	if err != nil {
+		return err
+	}
+	defer ciphertext.Close()
+
+	return c.writeFile(argOrStdio(args, 2), 0644, func(w io.Writer) error {
+		if err := rsa.DictionaryAttack(public, ciphertext, w); err != nil {
+			return attackError(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	rsa "github.com/Alextopher/crypto/hw2/myrsa"
+)
+
+// Signatures are RSASSA-PSS over the SHA-256 hash of the file
+var signatureOptions = &rsa.PSSOptions{
+	SaltLength: rsa.PSSSaltLengthEqualsHash,
+	Hash:       crypto.SHA256,
+}
+
+// The prime mode with the name given by String
+func parseMode(name string) (rsa.PrimeMode, error) {
+	for mode := rsa.ProbablePrimes; mode <= rsa.ProvablePrimes; mode++ {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown prime mode %q, expected probable, strong or provable", name)
+}
+
+// rsa keygen [-bits n] [-primes n] [-mode probable|strong|provable] <public_key> <private_key>
+func keygen(c *cli, cmd *command, args []string) error {
+	flags := c.flags(cmd)
+	bits := flags.Uint("bits", 2048, "size of the modulus in bits")
+	primes := flags.Int("primes", 2, "number of primes in the modulus")
+	mode := flags.String("mode", "probable", "how the primes are made: probable, strong or provable")
+
+	args, err := c.parse(cmd, flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	opts := rsa.KeygenOptions{Bits: *bits, Primes: *primes}
+	if opts.Mode, err = parseMode(*mode); err != nil {
+		return fail(exitUsage, "%w", err)
+	}
+
+	// Ask before generating the key so nobody waits for the prompt
+	passphrase, err := c.newPassphrase(passphraseEnv, args[1])
+	if err != nil {
+		return err
+	}
+
+	private, err := rsa.KeygenWithOptions(context.Background(), opts)
+	if err != nil {
+		return fail(exitCrypto, "generating key: %w", err)
+	}
+
+	if err := c.writeFile(args[0], 0644, private.Public.SavePEM); err != nil {
+		return err
+	}
+	return c.savePrivateKey(args[1], private, passphrase)
+}
+
+// rsa encrypt [-format envelope|decimal|raw|hex|base64] <public_key> [<plaintext> [<ciphertext>]]
+func encrypt(c *cli, cmd *command, args []string) error {
+	flags := c.flags(cmd)
+	format := flags.String("format", "envelope", "envelope, or raw RSA blocks as decimal, raw, hex or base64")
+
+	args, err := c.parse(cmd, flags, args, 1, 3)
+	if err != nil {
+		return err
+	}
+
+	var encoding rsa.Encoding
+	if *format != "envelope" {
+		if encoding, err = rsa.ParseEncoding(*format); err != nil {
+			return fail(exitUsage, "%w", err)
+		}
+	}
+
+	public, err := c.loadPublicKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := c.open(argOrStdio(args, 1))
+	if err != nil {
+		return err
+	}
+	defer plaintext.Close()
+
+	return c.writeFile(argOrStdio(args, 2), 0644, func(w io.Writer) error {
+		if *format == "envelope" {
+			err = public.EncryptEnvelope(plaintext, w)
+		} else {
+			err = public.EncryptEncoded(plaintext, w, encoding)
+		}
+
+		if err != nil {
+			return fail(exitCrypto, "encrypting: %w", err)
+		}
+		return nil
+	})
+}
+
+// rsa decrypt <private_key> [<ciphertext> [<plaintext>]]
+func decrypt(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 1, 3)
+	if err != nil {
+		return err
+	}
+
+	private, err := c.loadPrivateKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	ciphertextFile, err := c.open(argOrStdio(args, 1))
+	if err != nil {
+		return err
+	}
+	defer ciphertextFile.Close()
+
+	// Envelopes start with a magic string, anything else is raw RSA blocks, which Decrypt tells apart by their header
+	ciphertext := bufio.NewReader(ciphertextFile)
+	header, _ := ciphertext.Peek(len(rsa.EnvelopeMagic))
+
+	return c.writeFile(argOrStdio(args, 2), 0644, func(w io.Writer) error {
+		if rsa.IsEnvelope(header) {
+			err = private.DecryptEnvelope(ciphertext, w)
+		} else {
+			err = private.Decrypt(ciphertext, w)
+		}
+
+		if err != nil {
+			return fail(exitCrypto, "decrypting: %w", err)
+		}
+		return nil
+	})
+}
+
+// rsa sign <private_key> [<file> [<signature>]]
+func sign(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 1, 3)
+	if err != nil {
+		return err
+	}
+
+	private, err := c.loadPrivateKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	digest, err := c.hashFile(argOrStdio(args, 1))
+	if err != nil {
+		return err
+	}
+
+	signature, err := private.SignPSS(rand.Reader, crypto.SHA256, digest, signatureOptions)
+	if err != nil {
+		return fail(exitCrypto, "signing: %w", err)
+	}
+
+	return c.writeFile(argOrStdio(args, 2), 0644, func(w io.Writer) error {
+		_, err := w.Write(signature)
+		return err
+	})
+}
+
+// rsa verify <public_key> <file> <signature>
+func verify(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 3, 3)
+	if err != nil {
+		return err
+	}
+
+	if args[1] == "-" && args[2] == "-" {
+		return fail(exitUsage, "the file and the signature can't both be stdin")
+	}
+
+	public, err := c.loadPublicKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	digest, err := c.hashFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	signature, err := c.readFile(args[2])
+	if err != nil {
+		return err
+	}
+
+	if err := public.VerifyPSS(crypto.SHA256, digest, signature, signatureOptions); err != nil {
+		return fail(exitFailed, "signature is invalid")
+	}
+
+	fmt.Fprintln(c.stdout, "Signature is valid")
+	return nil
+}
+
+// rsa inspect <key>
+func inspect(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.readFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Private keys aren't checked, so a broken key can still be described
+	if rsa.IsEncryptedPrivateKey(data) {
+		private, err := c.parsePrivateKey(args[0], data, false)
+		if err != nil {
+			return err
+		}
+		return describeKey(c.stdout, "RSA private key, encrypted with a passphrase", private.Public, private.PrimeCount())
+	}
+
+	if private, err := rsa.ParsePrivateKeyUnchecked(data); err == nil {
+		return describeKey(c.stdout, "RSA private key", private.Public, private.PrimeCount())
+	}
+
+	public, err := rsa.ParsePublicKey(data)
+	if err != nil {
+		return fail(exitKey, "%s is not an RSA public or private key", args[0])
+	}
+
+	return describeKey(c.stdout, "RSA public key", public, 0)
+}
+
+// Prints the size, exponent and fingerprint of a key, the number of primes is left out when it's 0
+func describeKey(w io.Writer, kind string, public *rsa.PublicKey, primes int) error {
+	f, err := public.Fingerprint()
+	if err != nil {
+		return fail(exitKey, "fingerprinting key: %w", err)
+	}
+
+	fmt.Fprintln(w, "Type:        "+kind)
+	fmt.Fprintf(w, "Modulus:     %d bits\n", public.BitLen())
+	if primes > 0 {
+		fmt.Fprintf(w, "Primes:      %d\n", primes)
+	}
+	fmt.Fprintf(w, "Exponent:    %s\n", public.Exponent())
+	fmt.Fprintf(w, "Fingerprint: %s\n", f)
+	fmt.Fprint(w, f.Randomart(fmt.Sprintf("RSA %d", public.BitLen())))
+	return nil
+}
+
+// rsa check <private_key>
+func check(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	// Load the private key without rejecting it, so every problem can be reported
+	data, err := c.readFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	private, err := c.parsePrivateKey(args[0], data, false)
+	if err != nil {
+		return err
+	}
+
+	// Report every problem
+	report := private.Check()
+	for _, problem := range report.Errors {
+		fmt.Fprintln(c.stdout, "Error:", problem)
+	}
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(c.stdout, "Warning:", warning)
+	}
+
+	if len(report.Errors) > 0 {
+		return fail(exitFailed, "key is invalid")
+	}
+
+	fmt.Fprintln(c.stdout, "Key is valid")
+	return nil
+}
+
+// rsa passphrase <private_key>
+func passphrase(c *cli, cmd *command, args []string) error {
+	args, err := c.parse(cmd, c.flags(cmd), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "-" {
+		return fail(exitUsage, "the private key must be a file, it is rewritten in place")
+	}
+
+	private, err := c.loadPrivateKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := c.newPassphrase(newPassphraseEnv, args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.savePrivateKey(args[0], private, passphrase)
+}
@@ -1,3 +1,19 @@
 module github.com/Alextopher/crypto/hw2
 
-go 1.17
+go 1.21
+
+require (
+	github.com/Alextopher/crypto/fingerprint v0.0.0
+	github.com/Alextopher/crypto/keyfile v0.0.0
+	github.com/Alextopher/crypto/numtheory v0.0.0
+)
+
+require github.com/Alextopher/crypto/aes v0.0.0 // indirect
+
+replace github.com/Alextopher/crypto/aes => ../aes
+
+replace github.com/Alextopher/crypto/fingerprint => ../fingerprint
+
+replace github.com/Alextopher/crypto/keyfile => ../keyfile
+
+replace github.com/Alextopher/crypto/numtheory => ../numtheory
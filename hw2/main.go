@@ -1,130 +1,434 @@
 package main
 
 import (
+	"crypto/sha256"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"strconv"
+	"path/filepath"
 
 	rsa "github.com/Alextopher/crypto/hw2/myrsa"
+	"github.com/Alextopher/crypto/keyfile"
 )
 
+// Exit codes
+const (
+	exitOK     = 0
+	exitFailed = 1 // verify or check rejected the input, or attack broke nothing
+	exitUsage  = 2 // unknown command, bad flags or the wrong number of arguments
+	exitIO     = 3 // a file couldn't be read or written
+	exitKey    = 4 // a key couldn't be parsed or decrypted, or there was no passphrase
+	exitCrypto = 5 // key generation, encryption, decryption or signing failed
+)
+
+// An error and the exit code it ends the program with, err is nil if it was already printed
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return "exit status " + fmt.Sprint(e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// Wraps an error with an exit code
+func fail(code int, format string, args ...interface{}) error {
+	return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// The standard streams, "-" reads from stdin or writes to stdout
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// A subcommand of rsa
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, cmd *command, args []string) error
+}
+
+var commands = []*command{
+	{"keygen", "[-bits n] [-primes n] [-mode probable|strong|provable] <public_key> <private_key>", "generate a key pair", keygen},
+	{"encrypt", "[-format envelope|decimal|raw|hex|base64] <public_key> [<plaintext> [<ciphertext>]]", "encrypt a file for a public key", encrypt},
+	{"decrypt", "<private_key> [<ciphertext> [<plaintext>]]", "decrypt a file with a private key", decrypt},
+	{"sign", "<private_key> [<file> [<signature>]]", "sign the SHA-256 hash of a file", sign},
+	{"verify", "<public_key> <file> <signature>", "check a signature made by sign", verify},
+	{"inspect", "<key>", "describe a public or private key", inspect},
+	{"check", "<private_key>", "test that a private key holds together", check},
+	{"passphrase", "<private_key>", "add, change or remove the passphrase of a private key", passphrase},
+	{"attack", "<keys|broadcast|common|bytes> ...", "break weak keys and raw RSA ciphertexts", attack},
+}
+
 func main() {
-	// Keygen usage ./rsa keygen <key size> <public_key> <private_key>
-	// Encrypt usage ./rsa encrypt <public_key> <plaintext> <ciphertext>
-	// Decrypt usage ./rsa decrypt <private_key> <ciphertext> <plaintext>
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: ./rsa <keygen|encrypt|decrypt>")
-		fmt.Println("Usage: ./rsa keygen <key size> <public_key> <private_key>")
-		fmt.Println("Usage: ./rsa encrypt <public_key> <plaintext> <ciphertext>")
-		fmt.Println("Usage: ./rsa decrypt <private_key> <ciphertext> <plaintext>")
-		os.Exit(1)
-	}
-
-	if os.Args[1] == "keygen" {
-		if len(os.Args) != 5 {
-			panic("Usage: ./rsa keygen <key size> <public_key> <private_key>")
-		}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-		// Parse the key size
-		keySize, err := strconv.Atoi(os.Args[2])
+// Runs the command line and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
 
-		if err != nil {
-			fmt.Println("Invalid key size")
-			os.Exit(1)
-		}
+	flags := flag.NewFlagSet("rsa", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { c.usage(stderr) }
+	verbose := flags.Bool("v", false, "log key generation and decryption events to stderr")
 
-		if keySize%8 != 0 {
-			fmt.Println("Key size must be a multiple of 8")
-			os.Exit(1)
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
 		}
+		return exitUsage
+	}
+	args = flags.Args()
 
-		private := rsa.Keygen(uint(keySize))
+	if *verbose {
+		rsa.SetLogger(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
 
-		// Open the public key file
-		publicFile, err := os.Create(os.Args[3])
-		if err != nil {
-			fmt.Println("Error opening public key file", err)
-			os.Exit(1)
-		}
-		defer publicFile.Close()
+	if len(args) == 0 {
+		c.usage(stderr)
+		return exitUsage
+	}
 
-		// Open the private key file for writing (only user rewritable)
-		privateFile, err := os.OpenFile(os.Args[4], os.O_WRONLY|os.O_CREATE, 0600)
-		if err != nil {
-			fmt.Println("Error opening private key file", err)
-			os.Exit(1)
-		}
-		defer privateFile.Close()
-
-		// Save the keys
-		private.Public.Save(publicFile)
-		private.Save(privateFile)
-	} else if os.Args[1] == "encrypt" {
-		if len(os.Args) != 5 {
-			panic("Usage: ./rsa encrypt <public_key> <plaintext> <ciphertext>")
-		}
+	if args[0] == "help" {
+		c.usage(stdout)
+		return exitOK
+	}
 
-		// Open the public key file
-		publicFile, err := os.Open(os.Args[2])
-		if err != nil {
-			fmt.Println("Error opening public key file", err)
-			os.Exit(1)
+	var cmd *command
+	for _, candidate := range commands {
+		if candidate.name == args[0] {
+			cmd = candidate
 		}
-		defer publicFile.Close()
+	}
 
-		// Open the plaintext file
-		plaintextFile, err := os.Open(os.Args[3])
-		if err != nil {
-			fmt.Println("Error opening plaintext file", err)
-			os.Exit(1)
-		}
-		defer plaintextFile.Close()
+	if cmd == nil {
+		fmt.Fprintf(stderr, "rsa: unknown command %q\n", args[0])
+		c.usage(stderr)
+		return exitUsage
+	}
 
-		// Open the ciphertext file
-		ciphertextFile, err := os.Create(os.Args[4])
-		if err != nil {
-			fmt.Println("Error opening ciphertext file", err)
-			os.Exit(1)
+	err := cmd.run(c, cmd, args[1:])
+	if err == nil {
+		return exitOK
+	}
+
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+
+	var exit *exitError
+	if !errors.As(err, &exit) {
+		exit = &exitError{code: exitFailed, err: err}
+	}
+
+	if exit.err != nil {
+		fmt.Fprintf(stderr, "rsa %s: %s\n", cmd.name, err)
+	}
+	return exit.code
+}
+
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: rsa [-v] <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "-v logs key generation and decryption events to stderr. A file named - is stdin or stdout.")
+	fmt.Fprintln(w, "Run rsa <command> -h for the flags and arguments of a command.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Passphrases are read from the terminal, or from RSA_PASSPHRASE and RSA_NEW_PASSPHRASE when they are set.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 rejected by verify, check or attack, 2 usage, 3 file error, 4 bad key, 5 crypto error")
+}
+
+// A flag set for the command that prints its usage to stderr
+func (c *cli) flags(cmd *command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: rsa %s %s\n", cmd.name, cmd.args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// Parses the flags of a command and checks the number of arguments left over
+func (c *cli) parse(cmd *command, flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	// The flag package prints its own errors and the usage
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, err
 		}
-		defer ciphertextFile.Close()
+		return nil, &exitError{code: exitUsage}
+	}
+
+	args = flags.Args()
+	if len(args) < min || len(args) > max {
+		return nil, c.usageError(cmd)
+	}
+	return args, nil
+}
+
+func (c *cli) usageError(cmd *command) error {
+	return fail(exitUsage, "usage: rsa %s %s", cmd.name, cmd.args)
+}
+
+// The argument at i, or - if there aren't that many
+func argOrStdio(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return "-"
+}
+
+// Opens a file for reading, - is stdin
+func (c *cli) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(c.stdin), nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fail(exitIO, "%w", err)
+	}
+	return f, nil
+}
+
+// Reads a whole file, - is stdin
+func (c *cli) readFile(name string) ([]byte, error) {
+	r, err := c.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fail(exitIO, "reading %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// Writes to stdout without closing it
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (nopWriteCloser) abort() {}
+
+// A file written under a temporary name in the same directory and renamed over the real one when it is closed,
+// so readers see the old file or the whole new one, never a half written or stale file
+type atomicFile struct {
+	*os.File
+	name string
+}
+
+// Syncs the temporary file and renames it over the real one
+func (f *atomicFile) Close() error {
+	if err := f.Sync(); err != nil {
+		f.abort()
+		return err
+	}
+
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+
+	if err := os.Rename(f.File.Name(), f.name); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	return nil
+}
+
+// Throws the temporary file away, leaving the real one as it was
+func (f *atomicFile) abort() {
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
+
+// A file that is committed by Close or thrown away by abort
+type pendingFile interface {
+	io.WriteCloser
+	abort()
+}
+
+// Creates a file for writing with the given permissions, - is stdout.
+// Nothing is written to name until the file is closed, writes can fail on close.
+func (c *cli) create(name string, perm os.FileMode) (pendingFile, error) {
+	if name == "-" {
+		return nopWriteCloser{c.stdout}, nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return nil, fail(exitIO, "%w", err)
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fail(exitIO, "%w", err)
+	}
+
+	return &atomicFile{File: f, name: name}, nil
+}
+
+// Remembers the first error from the writer, to tell failed writes apart from other errors
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	n, err := ew.w.Write(p)
+	if err != nil && ew.err == nil {
+		ew.err = err
+	}
+	return n, err
+}
 
-		// Load the public key
-		public := rsa.ReadPublicKey(publicFile)
+// Creates a file, calls write with it and closes it, reporting the first error.
+// If anything fails the file named name is left untouched.
+// Errors from writing to the file end with exitIO, errors from write are returned as they are.
+func (c *cli) writeFile(name string, perm os.FileMode, write func(w io.Writer) error) error {
+	w, err := c.create(name, perm)
+	if err != nil {
+		return err
+	}
 
-		// Encrypt the plaintext
-		public.EncryptByByte(plaintextFile, ciphertextFile)
-	} else if os.Args[1] == "decrypt" {
-		if len(os.Args) != 5 {
-			panic("Usage: ./rsa decrypt <private_key> <ciphertext> <plaintext>")
+	ew := &errWriter{w: w}
+	if err := write(ew); err != nil || ew.err != nil {
+		w.abort()
+
+		if ew.err != nil {
+			return fail(exitIO, "writing %s: %w", name, ew.err)
 		}
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return fail(exitIO, "writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// Reads a public key file, PEM or the legacy decimal format
+func (c *cli) loadPublicKey(name string) (*rsa.PublicKey, error) {
+	data, err := c.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := rsa.ParsePublicKey(data)
+	if err != nil {
+		return nil, fail(exitKey, "%s: %w", name, err)
+	}
+	return public, nil
+}
+
+// The environment variables passphrases are read from instead of asking on the terminal
+const (
+	passphraseEnv    = "RSA_PASSPHRASE"
+	newPassphraseEnv = "RSA_NEW_PASSPHRASE"
+)
+
+// Reads a private key file, PEM or the legacy decimal format
+func (c *cli) loadPrivateKey(name string) (*rsa.PrivateKey, error) {
+	data, err := c.readFile(name)
+	if err != nil {
+		return nil, err
+	}
 
-		// Open the private key file
-		privateFile, err := os.Open(os.Args[2])
+	return c.parsePrivateKey(name, data, true)
+}
+
+// Parses a private key, asking for the passphrase if it is encrypted
+// Without validate a broken key can still be loaded, like rsa.ParsePrivateKeyUnchecked
+func (c *cli) parsePrivateKey(name string, data []byte, validate bool) (*rsa.PrivateKey, error) {
+	var private *rsa.PrivateKey
+	var err error
+
+	if rsa.IsEncryptedPrivateKey(data) {
+		passphrase, err := keyfile.ReadPassphrase(passphraseEnv, "Enter passphrase for "+name+": ")
 		if err != nil {
-			fmt.Println("Error opening private key file", err)
-			os.Exit(1)
+			return nil, fail(exitKey, "%s: %w", name, err)
 		}
-		defer privateFile.Close()
 
-		// Open the ciphertext file
-		ciphertextFile, err := os.Open(os.Args[3])
+		if validate {
+			private, err = rsa.ParseEncryptedPrivateKey(data, passphrase)
+		} else {
+			private, err = rsa.ParseEncryptedPrivateKeyUnchecked(data, passphrase)
+		}
 		if err != nil {
-			fmt.Println("Error opening ciphertext file", err)
-			os.Exit(1)
+			return nil, fail(exitKey, "%s: %w", name, err)
 		}
+		return private, nil
+	}
 
-		// Open the plaintext file
-		plaintextFile, err := os.Create(os.Args[4])
-		if err != nil {
-			fmt.Println("Error opening plaintext file", err)
-			os.Exit(1)
+	if validate {
+		private, err = rsa.ParsePrivateKey(data)
+	} else {
+		private, err = rsa.ParsePrivateKeyUnchecked(data)
+	}
+	if err != nil {
+		return nil, fail(exitKey, "%s: %w", name, err)
+	}
+	return private, nil
+}
+
+// Asks for the passphrase of a new private key file, an empty passphrase leaves the key unencrypted
+func (c *cli) newPassphrase(env, name string) ([]byte, error) {
+	passphrase, err := keyfile.NewPassphrase(env, "Enter a passphrase for "+name+" (empty for no passphrase): ")
+	if err != nil {
+		return nil, fail(exitKey, "%s: %w", name, err)
+	}
+	return passphrase, nil
+}
+
+// Writes a private key only readable by the user, encrypted unless the passphrase is empty
+func (c *cli) savePrivateKey(name string, private *rsa.PrivateKey, passphrase []byte) error {
+	return c.writeFile(name, 0600, func(w io.Writer) error {
+		if len(passphrase) == 0 {
+			return private.SavePEM(w)
 		}
-		defer plaintextFile.Close()
+		return private.SaveEncryptedPEM(w, passphrase)
+	})
+}
 
-		// Load the private key
-		private := rsa.ReadPrivateKey(privateFile)
+// Computes the SHA-256 hash of a file, - is stdin
+func (c *cli) hashFile(name string) ([]byte, error) {
+	r, err := c.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
 
-		// Decrypt the ciphertext
-		private.Decrypt(ciphertextFile, plaintextFile)
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fail(exitIO, "reading %s: %w", name, err)
 	}
+
+	return h.Sum(nil), nil
 }
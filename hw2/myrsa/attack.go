@@ -0,0 +1,94 @@
+package myrsa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// ErrAttackFailed
+// Error returned when an attack doesn't work against a key or ciphertext
+type ErrAttackFailed struct{}
+
+func (e ErrAttackFailed) Error() string {
+	return "attack failed"
+}
+
+// Rebuilds the private key of a two prime public key from one of its primes
+func recoverKey(public *PublicKey, p *big.Int) (*PrivateKey, error) {
+	one := big.NewInt(1)
+	n, e := public.n, public.e
+
+	q, rem := new(big.Int).QuoRem(n, p, new(big.Int))
+	if rem.Sign() != 0 || p.Cmp(one) <= 0 || q.Cmp(one) <= 0 {
+		return nil, fmt.Errorf("%w: %s is not a factor of n", ErrAttackFailed{}, p)
+	}
+
+	// d = e^-1 mod λ(n)
+	d, err := numtheory.ModInverse(e, carmichael(p, q))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAttackFailed{}, err)
+	}
+
+	private := newPrivateKey(new(big.Int).Set(n), new(big.Int).Set(e), d, []*big.Int{new(big.Int).Set(p), q})
+	if err := private.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: n is not the product of two primes: %s", ErrAttackFailed{}, err)
+	}
+
+	return private, nil
+}
+
+// An attack that recovers the private key from the public key alone
+type keyAttack struct {
+	name   string
+	attack func(public *PublicKey) (*PrivateKey, error)
+}
+
+// The single key attacks AttackKey tries, cheapest first
+var keyAttacks = []keyAttack{
+	{"Fermat", func(public *PublicKey) (*PrivateKey, error) { return FermatFactor(public, 1<<16) }},
+	{"Wiener", Wiener},
+	{"Pollard p-1", func(public *PublicKey) (*PrivateKey, error) { return PollardPM1(public, 1<<16) }},
+	{"Pollard rho", func(public *PublicKey) (*PrivateKey, error) { return PollardRho(public, 1<<16) }},
+}
+
+// AttackKey tries every single key attack with its default limits,
+// returning the private key and the name of the attack that found it
+func AttackKey(public *PublicKey) (*PrivateKey, string, error) {
+	for _, a := range keyAttacks {
+		private, err := a.attack(public)
+		if err == nil {
+			return private, a.name, nil
+		}
+
+		if !errors.As(err, &ErrAttackFailed{}) {
+			return nil, a.name, err
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w: no attack worked", ErrAttackFailed{})
+}
+
+// Reads every raw RSA block written by Encrypt or EncryptEncoded for a k byte modulus, and whether they are framed
+func readBlocks(r io.Reader, k int) ([]*big.Int, bool, error) {
+	br, err := newBlockReader(r, k)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var blocks []*big.Int
+	for {
+		c, err := br.next()
+		if err == io.EOF {
+			return blocks, br.framed, nil
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+		blocks = append(blocks, c)
+	}
+}
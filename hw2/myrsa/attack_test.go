@@ -0,0 +1,274 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// A random prime with gcd(p - 1, e) = 1
+func randomPrime(t *testing.T, bits int, e int64) *big.Int {
+	for {
+		p, err := numtheory.RandomPrime(rand.Reader, bits)
+		if err != nil {
+			t.Fatalf("Could not generate prime: %s", err)
+		}
+
+		if numtheory.GCD(new(big.Int).Sub(p, big.NewInt(1)), big.NewInt(e)).Cmp(big.NewInt(1)) == 0 {
+			return p
+		}
+	}
+}
+
+// The first prime after x with gcd(p - 1, e) = 1
+func nextPrime(x *big.Int, e int64) *big.Int {
+	p := new(big.Int).Add(x, big.NewInt(1))
+	p.SetBit(p, 0, 1)
+	for !p.ProbablyPrime(20) || numtheory.GCD(new(big.Int).Sub(p, big.NewInt(1)), big.NewInt(e)).Cmp(big.NewInt(1)) != 0 {
+		p.Add(p, big.NewInt(2))
+	}
+	return p
+}
+
+// Builds a key from chosen primes, for keys that are weak on purpose
+func weakKey(t *testing.T, e int64, p, q *big.Int) *PrivateKey {
+	E := big.NewInt(e)
+	d, err := numtheory.ModInverse(E, carmichael(p, q))
+	if err != nil {
+		t.Fatalf("e is not invertible: %s", err)
+	}
+
+	private := newPrivateKey(new(big.Int).Mul(p, q), E, d, []*big.Int{p, q})
+	if err := private.Validate(); err != nil {
+		t.Fatalf("Built an invalid key: %s", err)
+	}
+	return private
+}
+
+// The attack found the same primes as the key
+func expectBroken(t *testing.T, name string, private, recovered *PrivateKey, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+
+	if recovered.p.Cmp(private.p) != 0 || recovered.q.Cmp(private.q) != 0 {
+		t.Fatalf("%s found the wrong primes", name)
+	}
+
+	// The recovered key decrypts
+	m := big.NewInt(42)
+	c, _ := private.Public.encryptBlock(m.Bytes())
+	if d, err := recovered.decrypt_block(c); err != nil || d.Cmp(m) != 0 {
+		t.Fatalf("%s recovered a key that doesn't decrypt", name)
+	}
+}
+
+func expectFailed(t *testing.T, name string, err error) {
+	t.Helper()
+
+	if !errors.As(err, &ErrAttackFailed{}) {
+		t.Errorf("Expected %s to fail with ErrAttackFailed, got %v", name, err)
+	}
+}
+
+func TestFermatFactor(t *testing.T) {
+	// p and q share their top 240 bits
+	p := randomPrime(t, 512, 65537)
+	q := nextPrime(new(big.Int).Add(p, big.NewInt(1<<40)), 65537)
+	private := weakKey(t, 65537, q, p)
+
+	recovered, err := FermatFactor(private.Public, 1000)
+	expectBroken(t, "FermatFactor", private, recovered, err)
+
+	_, err = FermatFactor(testKey(t, 256).Public, 1000)
+	expectFailed(t, "FermatFactor", err)
+}
+
+func TestPollardPM1(t *testing.T) {
+	// p - 1 is a product of different primes below 2^16
+	one := big.NewInt(1)
+	smooth := numtheory.SmallPrimes(1 << 16)
+	var p *big.Int
+	for p == nil || !p.ProbablyPrime(20) || p.BitLen() < 256 {
+		p = big.NewInt(2)
+		used := map[int64]bool{}
+		for p.BitLen() < 256 {
+			i, _ := rand.Int(rand.Reader, big.NewInt(int64(len(smooth))))
+			if !used[i.Int64()] {
+				used[i.Int64()] = true
+				p.Mul(p, new(big.Int).SetUint64(smooth[i.Int64()]))
+			}
+		}
+		p.Add(p, one)
+
+		if numtheory.GCD(new(big.Int).Sub(p, one), big.NewInt(65537)).Cmp(one) != 0 {
+			p = nil
+		}
+	}
+
+	private := weakKey(t, 65537, p, randomPrime(t, 256, 65537))
+
+	recovered, err := PollardPM1(private.Public, 1<<16)
+	expectBroken(t, "PollardPM1", private, recovered, err)
+
+	_, err = PollardPM1(testKey(t, 256).Public, 1<<12)
+	expectFailed(t, "PollardPM1", err)
+}
+
+func TestPollardRho(t *testing.T) {
+	// q is only 32 bits
+	private := weakKey(t, 65537, randomPrime(t, 480, 65537), randomPrime(t, 32, 65537))
+
+	recovered, err := PollardRho(private.Public, 1<<20)
+	expectBroken(t, "PollardRho", private, recovered, err)
+
+	_, err = PollardRho(testKey(t, 256).Public, 1<<12)
+	expectFailed(t, "PollardRho", err)
+}
+
+func TestWiener(t *testing.T) {
+	p, q := randomPrime(t, 256, 1), randomPrime(t, 256, 1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Sub(q, big.NewInt(1)))
+
+	// d is 100 bits, below n^(1/4) / 3, and e = d^-1 mod φ(n) like Wiener assumes
+	var d, e *big.Int
+	for e == nil {
+		d, _ = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 100))
+		e, _ = numtheory.ModInverse(d, phi)
+	}
+
+	n := new(big.Int).Mul(p, q)
+	private := newPrivateKey(n, e, d, []*big.Int{p, q})
+	if err := private.Validate(); err != nil {
+		t.Fatalf("Built an invalid key: %s", err)
+	}
+
+	recovered, err := Wiener(private.Public)
+	expectBroken(t, "Wiener", private, recovered, err)
+
+	_, err = Wiener(testKey(t, 256).Public)
+	expectFailed(t, "Wiener", err)
+}
+
+func TestBatchGCD(t *testing.T) {
+	shared := randomPrime(t, 256, 65537)
+	keys := []*PrivateKey{
+		weakKey(t, 65537, shared, randomPrime(t, 256, 65537)),
+		testKey(t, 256),
+		testKey(t, 256),
+		weakKey(t, 65537, randomPrime(t, 256, 65537), shared),
+		testKey(t, 256),
+	}
+	keys = append(keys, keys[1])
+
+	public := make([]*PublicKey, len(keys))
+	for i, key := range keys {
+		public[i] = key.Public
+	}
+
+	results := BatchGCD(public)
+	for i, recovered := range results {
+		if i == 0 || i == 3 {
+			expectBroken(t, "BatchGCD", keys[i], recovered, nil)
+		} else if recovered != nil {
+			t.Errorf("BatchGCD broke key %d", i)
+		}
+	}
+
+	if results := BatchGCD(public[:1]); results[0] != nil {
+		t.Errorf("BatchGCD broke a single key")
+	}
+}
+
+func TestHastad(t *testing.T) {
+	message := "Attack at dawn! The same message went to everyone."
+
+	keys := make([]*PublicKey, 3)
+	ciphertexts := make([]*bytes.Buffer, 3)
+	readers := make([]io.Reader, 3)
+	for i := range keys {
+		keys[i] = weakKey(t, 3, randomPrime(t, 256, 3), randomPrime(t, 256, 3)).Public
+
+		ciphertexts[i] = &bytes.Buffer{}
+		if err := keys[i].Encrypt(strings.NewReader(message), ciphertexts[i]); err != nil {
+			t.Fatalf("Could not encrypt: %s", err)
+		}
+		readers[i] = bytes.NewReader(ciphertexts[i].Bytes())
+	}
+
+	plaintext := bytes.Buffer{}
+	if err := HastadDecrypt(keys, readers, &plaintext); err != nil {
+		t.Fatalf("HastadDecrypt failed: %s", err)
+	}
+
+	if plaintext.String() != message {
+		t.Errorf("HastadDecrypt recovered %q", plaintext.String())
+	}
+
+	// Two ciphertexts aren't enough for e = 3
+	_, err := Hastad(keys[:2], []*big.Int{big.NewInt(1), big.NewInt(1)})
+	expectFailed(t, "Hastad", err)
+
+	// Different messages
+	c := make([]*big.Int, 3)
+	for i, key := range keys {
+		c[i], _ = key.encryptBlock([]byte{byte(i + 1)})
+	}
+	_, err = Hastad(keys, c)
+	expectFailed(t, "Hastad", err)
+}
+
+func TestCommonModulus(t *testing.T) {
+	message := "Same modulus, different exponents."
+
+	p, q := randomPrime(t, 256, 3*65537), randomPrime(t, 256, 3*65537)
+	k1 := weakKey(t, 65537, p, q).Public
+	k2 := weakKey(t, 3, p, q).Public
+
+	c1, c2 := bytes.Buffer{}, bytes.Buffer{}
+	if err := k1.Encrypt(strings.NewReader(message), &c1); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	if err := k2.Encrypt(strings.NewReader(message), &c2); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	plaintext := bytes.Buffer{}
+	if err := CommonModulusDecrypt(k1, k2, &c1, &c2, &plaintext); err != nil {
+		t.Fatalf("CommonModulusDecrypt failed: %s", err)
+	}
+
+	if plaintext.String() != message {
+		t.Errorf("CommonModulusDecrypt recovered %q", plaintext.String())
+	}
+
+	_, err := CommonModulus(k1, testKey(t, 256).Public, big.NewInt(1), big.NewInt(1))
+	expectFailed(t, "CommonModulus", err)
+
+	_, err = CommonModulus(k1, k1, big.NewInt(1), big.NewInt(1))
+	expectFailed(t, "CommonModulus", err)
+}
+
+func TestAttackKey(t *testing.T) {
+	p := randomPrime(t, 256, 65537)
+	private := weakKey(t, 65537, nextPrime(new(big.Int).Add(p, big.NewInt(1<<20)), 65537), p)
+
+	recovered, name, err := AttackKey(private.Public)
+	expectBroken(t, "AttackKey", private, recovered, err)
+
+	if name != "Fermat" {
+		t.Errorf("Expected Fermat to break the key, got %s", name)
+	}
+
+	_, _, err = AttackKey(testKey(t, 256).Public)
+	expectFailed(t, "AttackKey", err)
+}
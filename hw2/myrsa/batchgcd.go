@@ -0,0 +1,63 @@
+package myrsa
+
+import (
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// BatchGCD finds the keys that share a prime with any of the others using Bernstein's product and remainder trees.
+// The result has the private key for every key it could factor and nil for the rest.
+// Keys with exactly the same modulus share both primes and can't be factored this way.
+func BatchGCD(keys []*PublicKey) []*PrivateKey {
+	results := make([]*PrivateKey, len(keys))
+	if len(keys) < 2 {
+		return results
+	}
+
+	// tree[0] holds the moduli and every level above holds the products of pairs from the one below
+	level := make([]*big.Int, len(keys))
+	for i, key := range keys {
+		level[i] = key.n
+	}
+	tree := [][]*big.Int{level}
+
+	for len(level) > 1 {
+		next := make([]*big.Int, (len(level)+1)/2)
+		for i := range next {
+			next[i] = new(big.Int).Set(level[2*i])
+			if 2*i+1 < len(level) {
+				next[i].Mul(next[i], level[2*i+1])
+			}
+		}
+		tree = append(tree, next)
+		level = next
+	}
+
+	// Walk back down, reducing the product mod the square of every node
+	remainders := tree[len(tree)-1]
+	for i := len(tree) - 2; i >= 0; i-- {
+		next := make([]*big.Int, len(tree[i]))
+		for j, x := range tree[i] {
+			square := new(big.Int).Mul(x, x)
+			next[j] = square.Mod(remainders[j/2], square)
+		}
+		remainders = next
+	}
+
+	// gcd(n, (product / n) mod n) = gcd(n, product mod n^2 / n)
+	one := big.NewInt(1)
+	for i, key := range keys {
+		r := new(big.Int).Quo(remainders[i], key.n)
+		d := numtheory.GCD(r, key.n)
+		if d.Cmp(one) == 0 || d.Cmp(key.n) == 0 {
+			continue
+		}
+
+		if private, err := recoverKey(key, d); err == nil {
+			results[i] = private
+		}
+	}
+
+	return results
+}
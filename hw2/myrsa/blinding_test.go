@@ -0,0 +1,91 @@
+package myrsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+// Replaces halfExponents for the rest of the test
+func injectHalfExponents(t *testing.T, fn func(a, b *big.Int, primes []*big.Int) []*big.Int) {
+	original := halfExponents
+	halfExponents = fn
+	t.Cleanup(func() { halfExponents = original })
+}
+
+// The CRT never sees the ciphertext itself
+func TestBlinding(t *testing.T) {
+	private := testKey(t, 256)
+	c := big.NewInt(123456789)
+
+	var inputs []*big.Int
+	injectHalfExponents(t, func(a, b *big.Int, primes []*big.Int) []*big.Int {
+		inputs = append(inputs, new(big.Int).Set(a))
+		return flt(a, b, primes)
+	})
+
+	first, err := private.decrypt_block(c)
+	if err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+
+	second, err := private.decrypt_block(c)
+	if err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+
+	if first.Cmp(second) != 0 {
+		t.Errorf("Blinding changed the result")
+	}
+
+	if len(inputs) != 2 || inputs[0].Cmp(c) == 0 || inputs[1].Cmp(c) == 0 || inputs[0].Cmp(inputs[1]) == 0 {
+		t.Errorf("The CRT was not run on a freshly blinded ciphertext")
+	}
+
+	// Unblinded the result is just c^d mod n
+	if first.Cmp(new(big.Int).Exp(c, private.d, private.Public.n)) != 0 {
+		t.Errorf("Decryption does not match c^d mod n")
+	}
+}
+
+// A fault in one half of the CRT leaks a prime, so the result must never be released
+func TestFaultInjection(t *testing.T) {
+	private := testKey(t, 512)
+	public := private.Public
+	c := big.NewInt(987654321)
+
+	// Corrupt the half computed mod p
+	injectHalfExponents(t, func(a, b *big.Int, primes []*big.Int) []*big.Int {
+		partials := flt(a, b, primes)
+		partials[0].Add(partials[0], big.NewInt(1))
+		return partials
+	})
+
+	// Without verification the faulty result factors n: gcd(m^e - c, n) = q
+	faulty := private.crt(c)
+	diff := new(big.Int).Exp(faulty, public.e, public.n)
+	diff.Sub(diff, c)
+	if factor := new(big.Int).GCD(nil, nil, diff.Abs(diff), public.n); factor.Cmp(private.q) != 0 {
+		t.Errorf("Expected the faulty result to leak q")
+	}
+
+	// With verification the fault is caught everywhere the private key is used
+	if _, err := private.decrypt_block(c); err != (ErrFault{}) {
+		t.Errorf("Expected ErrFault from decrypt_block, got %v", err)
+	}
+
+	if _, err := private.SignPKCS1v15(crypto.SHA256, digest(crypto.SHA256, "Hello World!")); err != (ErrFault{}) {
+		t.Errorf("Expected ErrFault from SignPKCS1v15, got %v", err)
+	}
+
+	ciphertext, err := public.EncryptOAEP(sha256.New(), rand.Reader, []byte("Hello World!"), nil)
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	if plaintext, err := private.DecryptOAEP(sha256.New(), ciphertext, nil); err == nil {
+		t.Errorf("DecryptOAEP released %q despite the fault", plaintext)
+	}
+}
@@ -0,0 +1,39 @@
+package myrsa
+
+import (
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// PrimalityTest chooses how keygen tests the candidates that survive trial division
+type PrimalityTest int
+
+const (
+	// WITNESS_COUNT rounds of Miller-Rabin with random bases
+	MillerRabin PrimalityTest = iota
+
+	// Baillie-PSW, Miller-Rabin with base 2 followed by a strong Lucas test.
+	// There is no known composite that passes it.
+	BailliePSW
+)
+
+func (test PrimalityTest) String() string {
+	switch test {
+	case MillerRabin:
+		return "Miller-Rabin"
+	case BailliePSW:
+		return "Baillie-PSW"
+	default:
+		return "unknown"
+	}
+}
+
+// Runs the test on a candidate
+func (test PrimalityTest) probablyPrime(n *big.Int) bool {
+	if test == BailliePSW {
+		return numtheory.BailliePSW(n)
+	}
+
+	return numtheory.MillerRabin(n, WITNESS_COUNT)
+}
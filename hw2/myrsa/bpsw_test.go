@@ -0,0 +1,21 @@
+package myrsa
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeygenBailliePSW(t *testing.T) {
+	private, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 1024, Test: BailliePSW})
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	if err := private.Validate(); err != nil {
+		t.Errorf("Key is invalid: %s", err)
+	}
+
+	if _, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 1024, Test: BailliePSW + 1}); err == nil {
+		t.Errorf("Generated a key with an unknown primality test")
+	}
+}
@@ -0,0 +1,183 @@
+package myrsa
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// The integer k-th root of x, rounded down, with Newton's method
+func iroot(x *big.Int, k int) *big.Int {
+	if x.Sign() == 0 {
+		return new(big.Int)
+	}
+
+	K := big.NewInt(int64(k))
+	km1 := big.NewInt(int64(k - 1))
+
+	// Start above the root and come down: y' = ((k-1)y + x / y^(k-1)) / k
+	y := new(big.Int).Lsh(big.NewInt(1), uint(x.BitLen()/k+1))
+	for {
+		t := new(big.Int).Exp(y, km1, nil)
+		t.Quo(x, t)
+		t.Add(t, new(big.Int).Mul(km1, y)).Quo(t, K)
+
+		if t.Cmp(y) >= 0 {
+			return y
+		}
+		y = t
+	}
+}
+
+// Hastad recovers m from the same unpadded message encrypted to e different keys that all use public exponent e.
+// The CRT gives m^e mod n_1·n_2·...·n_e, which is just m^e because m is smaller than every n_i.
+func Hastad(keys []*PublicKey, ciphertexts []*big.Int) (*big.Int, error) {
+	if len(keys) == 0 || len(keys) != len(ciphertexts) {
+		return nil, fmt.Errorf("%w: needs one ciphertext for every key", ErrAttackFailed{})
+	}
+
+	e := keys[0].e
+	if !e.IsInt64() || int64(len(keys)) < e.Int64() {
+		return nil, fmt.Errorf("%w: e = %s needs at least e ciphertexts, got %d", ErrAttackFailed{}, e, len(keys))
+	}
+
+	count := int(e.Int64())
+	moduli := make([]*big.Int, count)
+	for i, key := range keys[:count] {
+		if key.e.Cmp(e) != 0 {
+			return nil, fmt.Errorf("%w: every key must have the same e", ErrAttackFailed{})
+		}
+		moduli[i] = key.n
+	}
+
+	me, _, err := numtheory.CRT(ciphertexts[:count], moduli)
+	if err != nil {
+		// Moduli that share a factor are broken a different way, see BatchGCD
+		return nil, fmt.Errorf("%w: %s", ErrAttackFailed{}, err)
+	}
+
+	m := iroot(me, count)
+	if new(big.Int).Exp(m, e, nil).Cmp(me) != 0 {
+		return nil, fmt.Errorf("%w: the messages are not the same", ErrAttackFailed{})
+	}
+
+	return m, nil
+}
+
+// CommonModulus recovers m from the same message encrypted to two keys with the same n and coprime exponents.
+// With a·e1 + b·e2 = 1, c1^a · c2^b = m^(a·e1 + b·e2) = m mod n.
+func CommonModulus(k1, k2 *PublicKey, c1, c2 *big.Int) (*big.Int, error) {
+	n := k1.n
+	if n.Cmp(k2.n) != 0 {
+		return nil, fmt.Errorf("%w: the keys have different moduli", ErrAttackFailed{})
+	}
+
+	g, a, b := numtheory.ExtendedGCD(k1.e, k2.e)
+	if g.Cmp(big.NewInt(1)) != 0 {
+		return nil, fmt.Errorf("%w: the public exponents are not coprime", ErrAttackFailed{})
+	}
+
+	// A negative exponent is a positive one on the inverse
+	power := func(c, x *big.Int) (*big.Int, error) {
+		if x.Sign() < 0 {
+			inverse, err := numtheory.ModInverse(c, n)
+			if err != nil {
+				return nil, err
+			}
+			return inverse.Exp(inverse, new(big.Int).Neg(x), n), nil
+		}
+		return new(big.Int).Exp(c, x, n), nil
+	}
+
+	x, err := power(c1, a)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAttackFailed{}, err)
+	}
+
+	y, err := power(c2, b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAttackFailed{}, err)
+	}
+
+	return x.Mul(x, y).Mod(x, n), nil
+}
+
+// HastadDecrypt runs Hastad on every block of the files written by Encrypt or EncryptEncoded for each key, writing the plaintext to w
+func HastadDecrypt(keys []*PublicKey, ciphertexts []io.Reader, w io.Writer) error {
+	if len(keys) != len(ciphertexts) {
+		return fmt.Errorf("%w: needs one ciphertext for every key", ErrAttackFailed{})
+	}
+
+	blocks := make([][]*big.Int, len(keys))
+	framed := make([]bool, len(keys))
+	for i, r := range ciphertexts {
+		var err error
+		if blocks[i], framed[i], err = readBlocks(r, keys[i].size()); err != nil {
+			return err
+		}
+
+		if len(blocks[i]) != len(blocks[0]) || framed[i] != framed[0] {
+			return fmt.Errorf("%w: the ciphertexts have different lengths or formats", ErrAttackFailed{})
+		}
+	}
+
+	for j := range blocks[0] {
+		column := make([]*big.Int, len(keys))
+		for i := range keys {
+			column[i] = blocks[i][j]
+		}
+
+		m, err := Hastad(keys, column)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", j+1, err)
+		}
+
+		data, err := blockPlaintext(m, framed[0])
+		if err != nil {
+			return fmt.Errorf("block %d: %w: not a plaintext block", j+1, ErrAttackFailed{})
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CommonModulusDecrypt runs CommonModulus on every block of two files written by Encrypt or EncryptEncoded, writing the plaintext to w
+func CommonModulusDecrypt(k1, k2 *PublicKey, r1, r2 io.Reader, w io.Writer) error {
+	b1, framed, err := readBlocks(r1, k1.size())
+	if err != nil {
+		return err
+	}
+
+	b2, framed2, err := readBlocks(r2, k2.size())
+	if err != nil {
+		return err
+	}
+
+	if len(b1) != len(b2) || framed != framed2 {
+		return fmt.Errorf("%w: the ciphertexts have different lengths or formats", ErrAttackFailed{})
+	}
+
+	for j := range b1 {
+		m, err := CommonModulus(k1, k2, b1[j], b2[j])
+		if err != nil {
+			return fmt.Errorf("block %d: %w", j+1, err)
+		}
+
+		data, err := blockPlaintext(m, framed)
+		if err != nil {
+			return fmt.Errorf("block %d: %w: not a plaintext block", j+1, ErrAttackFailed{})
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package myrsa
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// Primes up to this size are proven by trial division instead of a certificate step
+const certificateBaseBits = 32
+
+// The most steps a certificate can have, a 2^16 bit prime needs 12
+const maxCertificateSteps = 64
+
+// ErrBadCertificate
+// Error returned when a certificate doesn't prove its prime
+type ErrBadCertificate struct{}
+
+func (e ErrBadCertificate) Error() string {
+	return "invalid primality certificate"
+}
+
+// PrimeCertificate proves that Prime is prime with Pocklington's theorem:
+// if q is a prime factor of n - 1 with q > √n - 1, and for some a
+//
+//	a^(n-1) = 1 mod n
+//	gcd(a^((n-1)/q) - 1, n) = 1
+//
+// then n is prime. Factor proves that q is prime in the same way, and the
+// last certificate in the chain holds a prime small enough for trial division.
+type PrimeCertificate struct {
+	Prime *big.Int
+
+	// The certificate for q, nil if Prime is at most certificateBaseBits long
+	Factor *PrimeCertificate
+
+	// The a from the theorem
+	Witness *big.Int
+}
+
+// Trial division, for numbers of at most certificateBaseBits
+func isSmallPrime(n uint64) bool {
+	if n < 2 {
+		return false
+	}
+
+	for i := uint64(2); i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Whether a number above sieveLimit is divisible by one of the small primes
+func hasSmallFactor(n *big.Int) bool {
+	m := new(big.Int)
+	for _, p := range smallPrimes {
+		if m.Mod(n, m.SetUint64(p)).Sign() == 0 {
+			return true
+		}
+	}
+
+	return n.Bit(0) == 0
+}
+
+// Verify checks every step of the certificate
+func (cert *PrimeCertificate) Verify() error {
+	one := big.NewInt(1)
+
+	for c, steps := cert, 0; ; c, steps = c.Factor, steps+1 {
+		if steps > maxCertificateSteps {
+			return fmt.Errorf("%w: more than %d steps", ErrBadCertificate{}, maxCertificateSteps)
+		}
+
+		if c == nil || c.Prime == nil || c.Prime.Sign() <= 0 {
+			return fmt.Errorf("%w: missing prime", ErrBadCertificate{})
+		}
+		n := c.Prime
+
+		if c.Factor == nil {
+			if n.BitLen() > certificateBaseBits || !isSmallPrime(n.Uint64()) {
+				return fmt.Errorf("%w: %s is not a small prime", ErrBadCertificate{}, n)
+			}
+			return nil
+		}
+
+		q, a := c.Factor.Prime, c.Witness
+		if q == nil || a == nil {
+			return fmt.Errorf("%w: missing factor or witness for %s", ErrBadCertificate{}, n)
+		}
+
+		// n - 1 = q * R
+		nm1 := new(big.Int).Sub(n, one)
+		R, rem := new(big.Int).QuoRem(nm1, q, new(big.Int))
+		if q.Sign() <= 0 || rem.Sign() != 0 {
+			return fmt.Errorf("%w: %s does not divide %s - 1", ErrBadCertificate{}, q, n)
+		}
+
+		// q > √n - 1, so (q + 1)^2 > n
+		if q1 := new(big.Int).Add(q, one); q1.Mul(q1, q1).Cmp(n) <= 0 {
+			return fmt.Errorf("%w: %s is too small to prove %s", ErrBadCertificate{}, q, n)
+		}
+
+		if a.Cmp(one) <= 0 || a.Cmp(nm1) >= 0 {
+			return fmt.Errorf("%w: witness for %s out of range", ErrBadCertificate{}, n)
+		}
+
+		// a^(n-1) = 1 mod n
+		if new(big.Int).Exp(a, nm1, n).Cmp(one) != 0 {
+			return fmt.Errorf("%w: %s is not a Fermat witness for %s", ErrBadCertificate{}, a, n)
+		}
+
+		// gcd(a^R - 1, n) = 1
+		x := new(big.Int).Exp(a, R, n)
+		if numtheory.GCD(n, x.Sub(x, one)).Cmp(one) != 0 {
+			return fmt.Errorf("%w: a^((%s - 1) / q) - 1 shares a factor with it", ErrBadCertificate{}, n)
+		}
+	}
+}
+
+// Save writes the certificate one step per line, each prime followed by its witness
+func (cert *PrimeCertificate) Save(w io.Writer) error {
+	for c := cert; c != nil; c = c.Factor {
+		line := c.Prime.String()
+		if c.Factor != nil {
+			line += " " + c.Witness.String()
+		}
+
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadPrimeCertificate reads a certificate written by Save, it still has to be verified
+func ReadPrimeCertificate(r io.Reader) (*PrimeCertificate, error) {
+	scanner := bufio.NewScanner(r)
+	var steps []*PrimeCertificate
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			break
+		}
+
+		if len(fields) > 2 || len(steps) == maxCertificateSteps+1 {
+			return nil, fmt.Errorf("%w: line %d is malformed", ErrBadCertificate{}, len(steps)+1)
+		}
+
+		step := &PrimeCertificate{}
+		for i, field := range fields {
+			x, ok := new(big.Int).SetString(field, 10)
+			if !ok {
+				return nil, fmt.Errorf("%w: line %d is not a number", ErrBadCertificate{}, len(steps)+1)
+			}
+
+			if i == 0 {
+				step.Prime = x
+			} else {
+				step.Witness = x
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: empty certificate", ErrBadCertificate{})
+	}
+
+	// Every line but the last has a witness
+	for i, step := range steps {
+		if (step.Witness != nil) != (i < len(steps)-1) {
+			return nil, fmt.Errorf("%w: line %d is malformed", ErrBadCertificate{}, i+1)
+		}
+
+		if i > 0 {
+			steps[i-1].Factor = step
+		}
+	}
+
+	return steps[0], nil
+}
+
+// A prime of size bits with its certificate, using Maurer's method:
+// prove a prime q a little over half the size, then search for n = 2Rq + 1
+func (s *primeSearch) provablePrime(bits uint, e *big.Int) (*PrimeCertificate, error) {
+	one := big.NewInt(1)
+
+	if bits <= certificateBaseBits {
+		for {
+			c, err := primeCandidate(s.opts.Random, bits)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := s.ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			s.tries++
+			if s.opts.Progress != nil {
+				s.opts.Progress(s.tries)
+			}
+
+			if isSmallPrime(c.Uint64()) && (e == nil || numtheory.GCD(new(big.Int).Sub(c, one), e).Cmp(one) == 0) {
+				return &PrimeCertificate{Prime: c}, nil
+			}
+		}
+	}
+
+	// q^2 > 2^bits > n
+	factor, err := s.provablePrime((bits+1)/2+1, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := factor.Prime
+
+	// 3·2^(bits-2) <= n < 2^bits keeps the top two bits set like primeCandidate
+	twoQ := new(big.Int).Lsh(q, 1)
+	low := new(big.Int).Lsh(big.NewInt(3), bits-2)
+	low.Add(low, twoQ).Sub(low, one).Quo(low, twoQ)
+	high := new(big.Int).Lsh(one, bits)
+	high.Sub(high, big.NewInt(2)).Quo(high, twoQ)
+	span := new(big.Int).Sub(high, low)
+	span.Add(span, one)
+
+	for {
+		R, err := rand.Int(s.opts.Random, span)
+		if err != nil {
+			return nil, err
+		}
+		R.Add(R, low)
+
+		n := new(big.Int).Mul(twoQ, R)
+		n.Add(n, one)
+
+		if hasSmallFactor(n) {
+			continue
+		}
+
+		ok, err := s.try(n, e)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		// A probable prime almost always has a small witness
+		nm1 := new(big.Int).Sub(n, one)
+		twoR := new(big.Int).Lsh(R, 1)
+		for a := big.NewInt(2); a.Cmp(big.NewInt(100)) < 0; a.Add(a, one) {
+			if new(big.Int).Exp(a, nm1, n).Cmp(one) != 0 {
+				break
+			}
+
+			x := new(big.Int).Exp(a, twoR, n)
+			if numtheory.GCD(n, x.Sub(x, one)).Cmp(one) == 0 {
+				return &PrimeCertificate{Prime: n, Factor: factor, Witness: a}, nil
+			}
+		}
+	}
+}
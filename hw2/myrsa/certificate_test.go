@@ -0,0 +1,138 @@
+package myrsa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// A certificate for a prime of the given size
+func testCertificate(t *testing.T, bits uint) *PrimeCertificate {
+	opts := &KeygenOptions{Random: seededReader(int64(bits))}
+	search := &primeSearch{ctx: context.Background(), opts: opts}
+
+	cert, err := search.provablePrime(bits, nil)
+	if err != nil {
+		t.Fatalf("Could not generate provable prime: %s", err)
+	}
+	return cert
+}
+
+func TestProvablePrime(t *testing.T) {
+	for _, bits := range []uint{16, 32, 33, 100, 512, 1024} {
+		cert := testCertificate(t, bits)
+
+		if cert.Prime.BitLen() != int(bits) || !cert.Prime.ProbablyPrime(20) {
+			t.Errorf("%s is not a %d bit prime", cert.Prime, bits)
+		}
+
+		if err := cert.Verify(); err != nil {
+			t.Errorf("Certificate for a %d bit prime does not verify: %s", bits, err)
+		}
+
+		buf := bytes.Buffer{}
+		if err := cert.Save(&buf); err != nil {
+			t.Fatalf("Could not save certificate: %s", err)
+		}
+
+		read, err := ReadPrimeCertificate(&buf)
+		if err != nil {
+			t.Fatalf("Could not read certificate: %s", err)
+		}
+
+		for a, b := cert, read; a != nil || b != nil; a, b = a.Factor, b.Factor {
+			if a == nil || b == nil || a.Prime.Cmp(b.Prime) != 0 || (a.Witness == nil) != (b.Witness == nil) ||
+				(a.Witness != nil && a.Witness.Cmp(b.Witness) != 0) {
+				t.Fatalf("Certificate changed in a round trip")
+			}
+		}
+	}
+}
+
+func TestCertificateForgeries(t *testing.T) {
+	cert := testCertificate(t, 256)
+	one := big.NewInt(1)
+
+	forgeries := map[string]func(c *PrimeCertificate){
+		"composite":          func(c *PrimeCertificate) { c.Prime = new(big.Int).Mul(c.Factor.Prime, big.NewInt(3)) },
+		"prime plus two":     func(c *PrimeCertificate) { c.Prime = new(big.Int).Add(c.Prime, big.NewInt(2)) },
+		"witness of one":     func(c *PrimeCertificate) { c.Witness = one },
+		"witness of n - 1":   func(c *PrimeCertificate) { c.Witness = new(big.Int).Sub(c.Prime, one) },
+		"missing witness":    func(c *PrimeCertificate) { c.Witness = nil },
+		"small factor":       func(c *PrimeCertificate) { c.Factor = &PrimeCertificate{Prime: big.NewInt(2)} },
+		"composite base":     func(c *PrimeCertificate) { last(c).Prime = new(big.Int).Add(last(c).Prime, one) },
+		"huge base":          func(c *PrimeCertificate) { last(c).Prime = c.Prime },
+		"cycle":              func(c *PrimeCertificate) { end := last(c); end.Factor, end.Witness = c, big.NewInt(2) },
+		"missing prime":      func(c *PrimeCertificate) { c.Factor.Prime = nil },
+		"composite in chain": func(c *PrimeCertificate) { c.Factor.Factor.Prime = big.NewInt(4) },
+		"non-Fermat witness": func(c *PrimeCertificate) {
+			c.Prime = new(big.Int).Add(new(big.Int).Mul(c.Factor.Prime, big.NewInt(1000)), one)
+		},
+	}
+
+	for name, forge := range forgeries {
+		forged := cloneCertificate(cert)
+		forge(forged)
+
+		if err := forged.Verify(); !errors.As(err, &ErrBadCertificate{}) {
+			t.Errorf("Expected ErrBadCertificate for %s, got %v", name, err)
+		}
+	}
+
+	// The original is untouched
+	if err := cert.Verify(); err != nil {
+		t.Errorf("Certificate does not verify: %s", err)
+	}
+}
+
+// The last step of a certificate
+func last(cert *PrimeCertificate) *PrimeCertificate {
+	for cert.Factor != nil {
+		cert = cert.Factor
+	}
+	return cert
+}
+
+func cloneCertificate(cert *PrimeCertificate) *PrimeCertificate {
+	if cert == nil {
+		return nil
+	}
+
+	clone := &PrimeCertificate{Prime: new(big.Int).Set(cert.Prime), Factor: cloneCertificate(cert.Factor)}
+	if cert.Witness != nil {
+		clone.Witness = new(big.Int).Set(cert.Witness)
+	}
+	return clone
+}
+
+func TestReadPrimeCertificateErrors(t *testing.T) {
+	inputs := []string{
+		"",
+		"\n",
+		"abc\n",
+		"7 2\n",
+		"23 5\n11 2\n",
+		"7\n3\n",
+		"23 5 7\n11\n",
+		strings.Repeat("23 5\n", maxCertificateSteps+2) + "11\n",
+	}
+
+	for _, input := range inputs {
+		if _, err := ReadPrimeCertificate(strings.NewReader(input)); !errors.As(err, &ErrBadCertificate{}) {
+			t.Errorf("Expected ErrBadCertificate for %q, got %v", input, err)
+		}
+	}
+
+	// Reading doesn't verify
+	cert, err := ReadPrimeCertificate(strings.NewReader("23 5\n11\n"))
+	if err != nil {
+		t.Fatalf("Could not read certificate: %s", err)
+	}
+
+	if cert.Prime.Int64() != 23 || cert.Factor.Prime.Int64() != 11 || cert.Verify() != nil {
+		t.Errorf("Read the wrong certificate")
+	}
+}
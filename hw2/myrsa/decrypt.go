@@ -1,49 +1,148 @@
 package myrsa
 
 import (
-	"bufio"
+	"crypto/rand"
+	"fmt"
 	"io"
 	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
 )
 
 // Prepares Chinese remainder theorem
+// For every prime r the coefficient a_r = 1 mod r and a_r = 0 mod every other prime
 func (private *PrivateKey) precompute() {
-	// c = m1 * p + m2 * q
-	_, m1, m2 := pulverizer(private.p, private.q)
-	m1 = m1.Mod(m1, private.p)
-	m2 = m2.Mod(m2, private.p)
+	n := private.Public.n
+	primes := private.primes()
+	coefficients := make([]*big.Int, len(primes))
+
+	for i, r := range primes {
+		// The product of the other primes
+		others := new(big.Int).Div(n, r)
+
+		// 1 = x * others + y * r
+		_, x, _ := numtheory.ExtendedGCD(new(big.Int).Mod(others, r), r)
+		x.Mod(x, r)
+
+		coefficients[i] = x.Mul(x, others)
+	}
+
+	private.coefficients = coefficients
+}
 
-	private.ap = m1.Mul(m1, private.p)
-	private.aq = m2.Mul(m2, private.q)
+// ErrFault
+// Error returned when the result of a private key operation doesn't re-encrypt to its input.
+// Releasing a faulty CRT result would let anyone factor n (the Bellcore attack), so it is thrown away.
+type ErrFault struct{}
+
+func (e ErrFault) Error() string {
+	return "RSA private key operation failed verification"
 }
 
-// Requires p > q
-func (private *PrivateKey) decrypt_block(c *big.Int) *big.Int {
-	if private.ap == nil || private.aq == nil {
+// Computes the partial results of the CRT, tests replace it to inject faults
+var halfExponents = flt
+
+// Applies the private key to c with the Chinese remainder theorem, without blinding or verification
+func (private *PrivateKey) crt(c *big.Int) *big.Int {
+	if private.coefficients == nil {
 		private.precompute()
 	}
 
-	// Fermat's little theorem
-	c1, c2 := flt(c, private.d, private.p, private.q)
+	// Fermat's little theorem, one partial result for each prime
+	partials := halfExponents(c, private.d, private.primes())
 
 	// Applies the Chinese remainder theorem
-	// m = c1 * p + c2 * q mod n
-	m := new(big.Int).Mul(c1, private.ap)
-	m.Add(m, new(big.Int).Mul(c2, private.aq))
+	// m = sum of partial * coefficient mod n
+	m := new(big.Int)
+	for i, partial := range partials {
+		m.Add(m, partial.Mul(partial, private.coefficients[i]))
+	}
 	return m.Mod(m, private.Public.n)
 }
 
-func (private *PrivateKey) Decrypt(r io.Reader, w io.Writer) {
-	// Each block is a hexadecimal number separated by a newline
-	scanner := bufio.NewScanner(r)
+// Picks a random r coprime to n and returns r and r^-1 mod n
+func (public *PublicKey) blindingFactor(random io.Reader) (*big.Int, *big.Int, error) {
+	for {
+		r, err := rand.Int(random, public.n)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	for scanner.Scan() {
-		chipher, _ := new(big.Int).SetString(scanner.Text(), 10)
+		rInv := new(big.Int).ModInverse(r, public.n)
+		if r.Sign() > 0 && rInv != nil {
+			return r, rInv, nil
+		}
+	}
+}
+
+// Applies the private key to c, which must be less than n
+//
+// The ciphertext is blinded with a fresh random r: the CRT runs on c * r^e, so the time
+// big.Int.Exp takes is unrelated to c, and the result is multiplied by r^-1 afterwards.
+// The CRT result is checked by re-encrypting it before it is unblinded.
+func (private *PrivateKey) decrypt_block(c *big.Int) (*big.Int, error) {
+	public := private.Public
+
+	r, rInv, err := public.blindingFactor(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// c' = c * r^e mod n
+	blinded := new(big.Int).Exp(r, public.e, public.n)
+	blinded.Mul(blinded, c)
+	blinded.Mod(blinded, public.n)
+
+	// m' = c'^d = m * r mod n
+	m := private.crt(blinded)
+
+	// m'^e must give c' back
+	if new(big.Int).Exp(m, public.e, public.n).Cmp(blinded) != 0 {
+		log().Warn("discarded a faulty CRT result", "bits", public.n.BitLen())
+		return nil, ErrFault{}
+	}
+
+	// m = m' * r^-1 mod n
+	m.Mul(m, rInv)
+	return m.Mod(m, public.n), nil
+}
+
+// Decrypt reverses Encrypt, EncryptEncoded and EncryptByByte, reading blocks in any encoding
+func (private *PrivateKey) Decrypt(r io.Reader, w io.Writer) error {
+	blocks, err := newBlockReader(r, private.Public.size())
+	if err != nil {
+		return err
+	}
+
+	for {
+		chipher, err := blocks.next()
+		if err == io.EOF {
+			log().Info("decrypted blocks", "encoding", blocks.enc, "framed", blocks.framed, "blocks", blocks.block-1)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if chipher.Cmp(private.Public.n) >= 0 {
+			return fmt.Errorf("block %d: %w", blocks.block, ErrDecryption{})
+		}
 
 		// Decrypt the block
-		plaintext := private.decrypt_block(chipher)
+		plaintext, err := private.decrypt_block(chipher)
+		if err != nil {
+			return err
+		}
+
+		data, err := blockPlaintext(plaintext, blocks.framed)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", blocks.block, err)
+		}
 
 		// Write the plaintext to the output
-		w.Write(plaintext.Bytes())
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
 	}
 }
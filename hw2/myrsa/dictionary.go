@@ -0,0 +1,46 @@
+package myrsa
+
+import (
+	"fmt"
+	"io"
+)
+
+// DictionaryAttack decrypts the output of EncryptByByte with only the public key.
+// Raw RSA is deterministic and there are only 256 possible bytes, so encrypting every one of them
+// gives a table from ciphertext back to plaintext.
+func DictionaryAttack(public *PublicKey, r io.Reader, w io.Writer) error {
+	table := map[string]byte{}
+	for b := 0; b < 256; b++ {
+		c, err := public.encryptBlock([]byte{byte(b)})
+		if err != nil {
+			// Only tiny moduli can't hold every byte
+			continue
+		}
+		table[c.String()] = byte(b)
+	}
+
+	blocks, err := newBlockReader(r, public.size())
+	if err != nil {
+		return err
+	}
+
+	for {
+		c, err := blocks.next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		b, ok := table[c.String()]
+		if !ok {
+			return fmt.Errorf("%w: block %d is not a single encrypted byte", ErrAttackFailed{}, blocks.block)
+		}
+
+		if _, err := w.Write([]byte{b}); err != nil {
+			return err
+		}
+	}
+}
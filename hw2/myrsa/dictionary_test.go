@@ -0,0 +1,45 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+// Anyone with the public key can read EncryptByByte output
+func TestDictionaryAttack(t *testing.T) {
+	private := testKey(t, 512)
+
+	msg := make([]byte, 1000)
+	rand.Read(msg)
+
+	ciphertext := bytes.Buffer{}
+	if err := private.Public.EncryptByByte(bytes.NewReader(msg), &ciphertext); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	plaintext := bytes.Buffer{}
+	if err := DictionaryAttack(private.Public, &ciphertext, &plaintext); err != nil {
+		t.Fatalf("Dictionary attack failed: %s", err)
+	}
+
+	if !bytes.Equal(plaintext.Bytes(), msg) {
+		t.Errorf("Dictionary attack recovered the wrong plaintext")
+	}
+}
+
+// Blocks of more than one byte have too many possible values for a table
+func TestDictionaryAttackFailsOnBlocks(t *testing.T) {
+	private := testKey(t, 512)
+
+	ciphertext := bytes.Buffer{}
+	if err := private.Public.Encrypt(bytes.NewReader([]byte("Hello World!")), &ciphertext); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	err := DictionaryAttack(private.Public, &ciphertext, &bytes.Buffer{})
+	if !errors.As(err, &ErrAttackFailed{}) {
+		t.Errorf("Expected ErrAttackFailed, got %v", err)
+	}
+}
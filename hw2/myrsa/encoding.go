@@ -0,0 +1,169 @@
+package myrsa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// Encoding is how EncryptEncoded writes raw RSA ciphertext blocks.
+// Every encoding but Decimal writes each block at the full size of the modulus.
+type Encoding int
+
+const (
+	// One decimal number per line, what Encrypt and EncryptByByte write
+	Decimal Encoding = iota
+
+	// Fixed-width big-endian blocks of k bytes, back to back
+	Raw
+
+	// One fixed-width hexadecimal block per line
+	Hex
+
+	// One base64 block per line
+	Base64
+)
+
+// BlocksMagic starts the header Encrypt and EncryptEncoded write, followed by a space, the name of the encoding and a newline.
+// Files without it are read as Decimal blocks that aren't framed, the format Encrypt used to write and EncryptByByte still does.
+const BlocksMagic = "MYRSABLK"
+
+// After the header every plaintext block is framed as 0x01 || data, so leading zero bytes of the data
+// survive the round trip through a number. 0x01 followed by k - 2 bytes is always smaller than n.
+const blockMarker = 0x01
+
+var encodingNames = []string{"decimal", "raw", "hex", "base64"}
+
+func (enc Encoding) String() string {
+	if enc < Decimal || enc > Base64 {
+		return "unknown"
+	}
+	return encodingNames[enc]
+}
+
+// ParseEncoding returns the Encoding with the name given by String
+func ParseEncoding(name string) (Encoding, error) {
+	for i, n := range encodingNames {
+		if n == name {
+			return Encoding(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown encoding %q, expected one of %s", name, strings.Join(encodingNames, ", "))
+}
+
+// Writes a ciphertext block c of a k byte modulus
+func (enc Encoding) writeBlock(w io.Writer, c *big.Int, k int) error {
+	var err error
+	switch enc {
+	case Decimal:
+		_, err = io.WriteString(w, c.String()+"\n")
+	case Raw:
+		_, err = w.Write(c.FillBytes(make([]byte, k)))
+	case Hex:
+		_, err = io.WriteString(w, hex.EncodeToString(c.FillBytes(make([]byte, k)))+"\n")
+	case Base64:
+		_, err = io.WriteString(w, base64.StdEncoding.EncodeToString(c.FillBytes(make([]byte, k)))+"\n")
+	default:
+		err = fmt.Errorf("unknown encoding %d", enc)
+	}
+	return err
+}
+
+// The data of a plaintext block m, without the 0x01 marker if the blocks are framed
+func blockPlaintext(m *big.Int, framed bool) ([]byte, error) {
+	b := m.Bytes()
+	if !framed {
+		return b, nil
+	}
+
+	if len(b) == 0 || b[0] != blockMarker {
+		return nil, ErrDecryption{}
+	}
+	return b[1:], nil
+}
+
+// Reads ciphertext blocks in any encoding
+type blockReader struct {
+	r      *bufio.Reader
+	enc    Encoding
+	framed bool
+	k      int
+	block  int
+}
+
+// Reads the header if there is one, otherwise the blocks are Decimal and not framed
+func newBlockReader(r io.Reader, k int) (*blockReader, error) {
+	br := &blockReader{r: bufio.NewReader(r), enc: Decimal, k: k}
+
+	start, _ := br.r.Peek(len(BlocksMagic))
+	if !bytes.Equal(start, []byte(BlocksMagic)) {
+		return br, nil
+	}
+
+	header, err := br.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading block header: %w", err)
+	}
+
+	name := strings.TrimPrefix(strings.TrimSpace(header), BlocksMagic+" ")
+	br.enc, err = ParseEncoding(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading block header: %w", err)
+	}
+	br.framed = true
+
+	return br, nil
+}
+
+// The next block, or io.EOF after the last one
+func (br *blockReader) next() (*big.Int, error) {
+	br.block++
+
+	if br.enc == Raw {
+		b := make([]byte, br.k)
+		if _, err := io.ReadFull(br.r, b); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("block %d: %w", br.block, ErrDecryption{})
+			}
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	line, err := br.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	} else if err != nil && err != io.EOF {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	var c *big.Int
+	ok := true
+	switch br.enc {
+	case Decimal:
+		c, ok = new(big.Int).SetString(line, 10)
+		ok = ok && c.Sign() >= 0
+	case Hex:
+		var b []byte
+		b, err = hex.DecodeString(line)
+		ok = err == nil && len(b) == br.k
+		c = new(big.Int).SetBytes(b)
+	case Base64:
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(line)
+		ok = err == nil && len(b) == br.k
+		c = new(big.Int).SetBytes(b)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("block %d: %w", br.block, ErrDecryption{})
+	}
+	return c, nil
+}
@@ -0,0 +1,129 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var encodings = []Encoding{Decimal, Raw, Hex, Base64}
+
+func TestEncryptEncoded(t *testing.T) {
+	private := testKey(t, 512)
+
+	msg := bytes.Repeat([]byte("Hello World!"), 100)
+
+	for _, enc := range encodings {
+		ciphertext := bytes.Buffer{}
+		if err := private.Public.EncryptEncoded(bytes.NewReader(msg), &ciphertext, enc); err != nil {
+			t.Fatalf("%s: could not encrypt: %s", enc, err)
+		}
+
+		if header := BlocksMagic + " " + enc.String() + "\n"; !bytes.HasPrefix(ciphertext.Bytes(), []byte(header)) {
+			t.Errorf("%s: ciphertext does not start with %q", enc, header)
+		}
+
+		plaintext := bytes.Buffer{}
+		if err := private.Decrypt(&ciphertext, &plaintext); err != nil {
+			t.Fatalf("%s: could not decrypt: %s", enc, err)
+		}
+
+		if !bytes.Equal(plaintext.Bytes(), msg) {
+			t.Errorf("%s: decrypted message does not match original", enc)
+		}
+	}
+}
+
+// Raw blocks are the size of the modulus, decimal blocks are about log10(256) ≈ 2.4 times longer
+func TestEncodingSize(t *testing.T) {
+	private := testKey(t, 512)
+	msg := bytes.Repeat([]byte("Hello World!"), 100)
+
+	sizes := map[Encoding]int{}
+	for _, enc := range encodings {
+		ciphertext := bytes.Buffer{}
+		if err := private.Public.EncryptEncoded(bytes.NewReader(msg), &ciphertext, enc); err != nil {
+			t.Fatalf("%s: could not encrypt: %s", enc, err)
+		}
+		sizes[enc] = ciphertext.Len()
+	}
+
+	if ratio := float64(sizes[Decimal]) / float64(sizes[Raw]); ratio < 2.3 {
+		t.Errorf("Decimal output is only %.2f times bigger than raw", ratio)
+	}
+
+	if sizes[Raw] >= sizes[Base64] || sizes[Base64] >= sizes[Hex] {
+		t.Errorf("Expected raw < base64 < hex, got %v", sizes)
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for _, enc := range encodings {
+		parsed, err := ParseEncoding(enc.String())
+		if err != nil || parsed != enc {
+			t.Errorf("ParseEncoding(%q) = %s, %v", enc.String(), parsed, err)
+		}
+	}
+
+	if _, err := ParseEncoding("octal"); err == nil {
+		t.Errorf("Parsed an unknown encoding")
+	}
+
+	if err := testKey(t, 512).Public.EncryptEncoded(strings.NewReader("A"), &bytes.Buffer{}, Encoding(7)); err == nil {
+		t.Errorf("Encrypted with an unknown encoding")
+	}
+}
+
+func TestDecryptMalformedBlocks(t *testing.T) {
+	private := testKey(t, 512)
+
+	// A truncated decimal block is still a number, the other encodings are fixed width
+	for _, enc := range []Encoding{Raw, Hex, Base64} {
+		ciphertext := bytes.Buffer{}
+		if err := private.Public.EncryptEncoded(strings.NewReader("Hello World!"), &ciphertext, enc); err != nil {
+			t.Fatalf("%s: could not encrypt: %s", enc, err)
+		}
+
+		// Cut the last block short, leaving the newline of the line encodings
+		data := ciphertext.Bytes()
+		truncated := append(append([]byte{}, data[:len(data)-3]...), '\n')
+		if enc == Raw {
+			truncated = data[:len(data)-2]
+		}
+
+		err := private.Decrypt(bytes.NewReader(truncated), io.Discard)
+		if !errors.As(err, &ErrDecryption{}) {
+			t.Errorf("%s: expected ErrDecryption for a truncated block, got %v", enc, err)
+		}
+	}
+
+	header := BlocksMagic + " octal\n1234\n"
+	if err := private.Decrypt(strings.NewReader(header), io.Discard); err == nil {
+		t.Errorf("Decrypted blocks in an unknown encoding")
+	}
+}
+
+func BenchmarkDecodeBlocks(b *testing.B) {
+	private := testKey(b, 1024)
+	msg := make([]byte, 64*1024)
+	rand.Read(msg)
+
+	for _, enc := range encodings {
+		ciphertext := bytes.Buffer{}
+		if err := private.Public.EncryptEncoded(bytes.NewReader(msg), &ciphertext, enc); err != nil {
+			b.Fatalf("%s: could not encrypt: %s", enc, err)
+		}
+
+		b.Run(enc.String(), func(b *testing.B) {
+			b.SetBytes(int64(ciphertext.Len()))
+			for i := 0; i < b.N; i++ {
+				if _, _, err := readBlocks(bytes.NewReader(ciphertext.Bytes()), private.Public.size()); err != nil {
+					b.Fatalf("Could not read blocks: %s", err)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package myrsa
 
 import (
+	"fmt"
 	"io"
 	"math/big"
 )
@@ -23,60 +24,91 @@ func (public *PublicKey) encryptBlock(block []byte) (*big.Int, error) {
 	return new(big.Int).Exp(m, public.e, public.n), nil
 }
 
-func (public *PublicKey) Encrypt(r io.Reader, w io.Writer) {
-	// block size
-	bs := public.n.BitLen() / 16
+// Encrypt encrypts everything read from r as raw RSA blocks, written to w as one decimal number per line after a header
+func (public *PublicKey) Encrypt(r io.Reader, w io.Writer) error {
+	return public.EncryptEncoded(r, w, Decimal)
+}
+
+// EncryptEncoded encrypts like Encrypt but writes the blocks in the given encoding, after a header naming it.
+// Decrypt reads every encoding.
+//
+// The plaintext is cut into blocks of k - 2 bytes, where k is the size of n in bytes, and each
+// block is framed as 0x01 || data before it is encrypted. Only the last block can be shorter.
+func (public *PublicKey) EncryptEncoded(r io.Reader, w io.Writer, enc Encoding) error {
+	if enc < Decimal || enc > Base64 {
+		return fmt.Errorf("unknown encoding %d", enc)
+	}
+
+	// The marker and at least one byte of data must fit below n
+	k := public.size()
+	if k < 3 {
+		return ErrMessageTooLong{}
+	}
+
+	if _, err := io.WriteString(w, BlocksMagic+" "+enc.String()+"\n"); err != nil {
+		return err
+	}
+
+	block := make([]byte, k-1)
+	block[0] = blockMarker
 
 	for {
-		// read a block
-		block := make([]byte, bs)
-		n, err := r.Read(block)
-		if err != nil {
-			if err == io.EOF {
-				break
+		// read a full block, or what is left at the end
+		n, err := io.ReadFull(r, block[1:])
+		if n > 0 {
+			// encrypt it
+			ciphertext, err := public.encryptBlock(block[:1+n])
+			if err != nil {
+				return err
+			}
+
+			// write it to the output
+			if err := enc.writeBlock(w, ciphertext, k); err != nil {
+				return err
 			}
 		}
 
-		// encrypt it
-		ciphertext, err := public.encryptBlock(block[:n])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
 
 		if err != nil {
-			panic(err)
+			return err
 		}
-
-		// write it to the output
-		w.Write([]byte(ciphertext.String()))
-
-		// Seperate with a newline
-		w.Write([]byte("\n"))
 	}
 }
 
-func (public *PublicKey) EncryptByByte(r io.Reader, w io.Writer) {
+// EncryptByByte encrypts every byte read from r on its own, written to w as one decimal number per line
+func (public *PublicKey) EncryptByByte(r io.Reader, w io.Writer) error {
 	// read buffer
 	buf := make([]byte, 4096)
 
 	for {
 		// read a block
 		n, err := r.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-		}
 
 		// loop for each byte
 		for i := 0; i < n; i++ {
 			b := buf[i]
 
 			// encrypt it
-			ciphertext := new(big.Int).Exp(big.NewInt(int64(b)), public.e, public.n)
+			ciphertext, encErr := public.encryptBlock([]byte{b})
+			if encErr != nil {
+				return encErr
+			}
 
-			// write it to the output
-			w.Write([]byte(ciphertext.String()))
+			// write it to the output, seperated with a newline
+			if _, err := io.WriteString(w, ciphertext.String()+"\n"); err != nil {
+				return err
+			}
+		}
 
-			// Seperate with a newline
-			w.Write([]byte("\n"))
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
 		}
 	}
 }
@@ -0,0 +1,135 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Random binary data, with runs of zero bytes where blocks start
+func randomFile(t *testing.T, size, block int) []byte {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("Could not generate data: %s", err)
+	}
+
+	for i := 0; i < size; i += block {
+		for j := i; j < i+3 && j < size; j++ {
+			data[j] = 0
+		}
+	}
+	return data
+}
+
+func TestEncryptBinary(t *testing.T) {
+	private := testKey(t, 256)
+	k := private.Public.size()
+	bs := k - 2
+
+	sizes := []int{0, 1, bs - 1, bs, bs + 1, 3 * bs, 1000}
+	for i := 0; i < 5; i++ {
+		n, _ := rand.Int(rand.Reader, big.NewInt(4096))
+		sizes = append(sizes, int(n.Int64()))
+	}
+
+	for _, size := range sizes {
+		msg := randomFile(t, size, bs)
+
+		for _, enc := range encodings {
+			ciphertext := bytes.Buffer{}
+			if err := private.Public.EncryptEncoded(bytes.NewReader(msg), &ciphertext, enc); err != nil {
+				t.Fatalf("%s: could not encrypt %d bytes: %s", enc, size, err)
+			}
+
+			plaintext := bytes.Buffer{}
+			if err := private.Decrypt(&ciphertext, &plaintext); err != nil {
+				t.Fatalf("%s: could not decrypt %d bytes: %s", enc, size, err)
+			}
+
+			if !bytes.Equal(plaintext.Bytes(), msg) {
+				t.Errorf("%s: decrypted %d bytes does not match the original %d bytes", enc, plaintext.Len(), size)
+			}
+		}
+	}
+}
+
+// Every block but the last is full, whatever the reader returns
+func TestEncryptBlockSize(t *testing.T) {
+	private := testKey(t, 512)
+	k := private.Public.size()
+	msg := randomFile(t, 10*(k-2)+5, k-2)
+
+	readers := map[string]io.Reader{
+		"whole":    bytes.NewReader(msg),
+		"one byte": iotest.OneByteReader(bytes.NewReader(msg)),
+		"half":     iotest.HalfReader(bytes.NewReader(msg)),
+	}
+
+	for name, r := range readers {
+		ciphertext := bytes.Buffer{}
+		if err := private.Public.EncryptEncoded(r, &ciphertext, Raw); err != nil {
+			t.Fatalf("%s: could not encrypt: %s", name, err)
+		}
+
+		// The header and 11 blocks of k bytes
+		header := len(BlocksMagic + " raw\n")
+		if blocks := (ciphertext.Len() - header) / k; blocks != 11 || (ciphertext.Len()-header)%k != 0 {
+			t.Errorf("%s: expected 11 blocks of %d bytes, got %d bytes", name, k, ciphertext.Len()-header)
+		}
+
+		// Each block holds k - 2 bytes of data after the marker
+		blocks, framed, err := readBlocks(&ciphertext, k)
+		if err != nil || !framed {
+			t.Fatalf("%s: could not read blocks: %s", name, err)
+		}
+
+		for i, c := range blocks {
+			m, err := private.decrypt_block(c)
+			if err != nil {
+				t.Fatalf("%s: could not decrypt block %d: %s", name, i, err)
+			}
+
+			expected := k - 1
+			if i == len(blocks)-1 {
+				expected = 6
+			}
+
+			if m.BitLen() != 8*(expected-1)+1 {
+				t.Errorf("%s: block %d is %d bits, expected 0x01 and %d bytes", name, i, m.BitLen(), expected-1)
+			}
+		}
+	}
+}
+
+// Files from before blocks were framed have no header and are decrypted as they are
+func TestDecryptUnframed(t *testing.T) {
+	private := testKey(t, 256)
+
+	legacy := ""
+	for _, block := range []string{"Hello", " World!"} {
+		c, err := private.Public.encryptBlock([]byte(block))
+		if err != nil {
+			t.Fatalf("Could not encrypt: %s", err)
+		}
+		legacy += c.String() + "\n"
+	}
+
+	plaintext := bytes.Buffer{}
+	if err := private.Decrypt(strings.NewReader(legacy), &plaintext); err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+
+	if plaintext.String() != "Hello World!" {
+		t.Errorf("Decrypted %q", plaintext.String())
+	}
+
+	// A framed file with a block that doesn't start with the marker
+	c, _ := private.Public.encryptBlock([]byte("Hello"))
+	if err := private.Decrypt(strings.NewReader(BlocksMagic+" decimal\n"+c.String()+"\n"), io.Discard); err == nil {
+		t.Errorf("Decrypted a block without the marker")
+	}
+}
@@ -0,0 +1,307 @@
+package myrsa
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Hybrid encryption: RSA only protects a random value z, and the file itself is encrypted with AES-GCM
+// under a key derived from z (RSA-KEM from RFC 5990, with KDF2 and SHA-256).
+//
+// An envelope is laid out as
+//
+//	magic "MYRSAENV" | version (1 byte)
+//	algorithm name   (2 byte length + bytes)
+//	key ID           (2 byte length + bytes)
+//	z^e mod n        (2 byte length + bytes)
+//	chunk size       (4 bytes)
+//	chunks...
+//
+// Every chunk holds chunk size bytes of plaintext except the last, which may be shorter or even empty.
+// Chunks are sealed with the header as associated data and the nonce counter || last flag,
+// so reordering, truncating or extending the stream is detected.
+
+// EnvelopeMagic starts every envelope
+const EnvelopeMagic = "MYRSAENV"
+
+// The only algorithm envelopes are currently written with
+const EnvelopeAlgorithm = "RSA-KEM-KDF2-SHA256+AES-256-GCM"
+
+const envelopeVersion = 1
+
+// The number of plaintext bytes in every chunk but the last
+const envelopeChunkSize = 64 * 1024
+
+// ErrWrongKey
+// Error returned when an envelope was encrypted for a different key
+type ErrWrongKey struct{}
+
+func (e ErrWrongKey) Error() string {
+	return "envelope was encrypted for a different key"
+}
+
+// KeyID identifies the public key, it is the SHA-256 hash of the length prefixed modulus and exponent
+func (public *PublicKey) KeyID() []byte {
+	h := sha256.New()
+	for _, x := range []*big.Int{public.n, public.e} {
+		b := x.Bytes()
+		binary.Write(h, binary.BigEndian, uint32(len(b)))
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// IsEnvelope reports if the start of a file looks like an envelope.
+// header needs to be at least len(EnvelopeMagic) bytes long.
+func IsEnvelope(header []byte) bool {
+	return bytes.HasPrefix(header, []byte(EnvelopeMagic))
+}
+
+// KDF2 from ISO 18033-2 with SHA-256
+// Hash(z || counter) for counter = 1, 2, ... truncated to length bytes
+func kdf2(z []byte, length int) []byte {
+	out := make([]byte, 0, length)
+	for counter := uint32(1); len(out) < length; counter++ {
+		h := sha256.New()
+		h.Write(z)
+		binary.Write(h, binary.BigEndian, counter)
+		out = h.Sum(out)
+	}
+	return out[:length]
+}
+
+// Builds the AES-256-GCM cipher from the KEM secret
+func (public *PublicKey) envelopeAEAD(z *big.Int) (cipher.AEAD, error) {
+	key := kdf2(z.FillBytes(make([]byte, public.size())), 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// The nonce for chunk i: 7 zero bytes, the counter and a flag for the last chunk.
+// Every envelope has a new key, so the counter alone keeps nonces unique.
+func envelopeNonce(i uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:11], i)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// Appends a 2 byte length followed by b
+func appendField(header, b []byte) []byte {
+	header = append(header, byte(len(b)>>8), byte(len(b)))
+	return append(header, b...)
+}
+
+// Reads a field written by appendField
+func readField(r io.Reader, header *bytes.Buffer) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	binary.Write(header, binary.BigEndian, length)
+	header.Write(b)
+	return b, nil
+}
+
+// EncryptEnvelope encrypts everything read from r into an envelope written to w
+func (public *PublicKey) EncryptEnvelope(r io.Reader, w io.Writer) error {
+	// Encapsulate a random z < n
+	z, err := rand.Int(rand.Reader, public.n)
+	if err != nil {
+		return err
+	}
+
+	c, err := public.encryptBlock(z.Bytes())
+	if err != nil {
+		return err
+	}
+
+	aead, err := public.envelopeAEAD(z)
+	if err != nil {
+		return err
+	}
+
+	// Write the header
+	header := append([]byte(EnvelopeMagic), envelopeVersion)
+	header = appendField(header, []byte(EnvelopeAlgorithm))
+	header = appendField(header, public.KeyID())
+	header = appendField(header, c.FillBytes(make([]byte, public.size())))
+	chunkSize := make([]byte, 4)
+	binary.BigEndian.PutUint32(chunkSize, envelopeChunkSize)
+	header = append(header, chunkSize...)
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// Read one chunk ahead so we know which one is last
+	br := bufio.NewReaderSize(r, envelopeChunkSize)
+	chunk := make([]byte, envelopeChunkSize)
+	sealed := make([]byte, 0, envelopeChunkSize+aead.Overhead())
+
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		last := n < envelopeChunkSize
+		if !last {
+			_, err := br.Peek(1)
+			if err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		sealed = aead.Seal(sealed[:0], envelopeNonce(i, last), chunk[:n], header)
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+
+		if i == ^uint32(0) {
+			return errors.New("too many chunks for one envelope")
+		}
+	}
+}
+
+// DecryptEnvelope decrypts an envelope read from r and writes the plaintext to w.
+// Plaintext is written one authenticated chunk at a time, so if an error is returned
+// part of the output may already have been written and should be discarded.
+func (private *PrivateKey) DecryptEnvelope(r io.Reader, w io.Writer) error {
+	public := private.Public
+	header := &bytes.Buffer{}
+
+	// Check the magic and version
+	start := make([]byte, len(EnvelopeMagic)+1)
+	if _, err := io.ReadFull(r, start); err != nil {
+		return fmt.Errorf("reading envelope header: %w", err)
+	}
+
+	if !IsEnvelope(start) {
+		return errors.New("not an envelope")
+	}
+
+	if start[len(start)-1] != envelopeVersion {
+		return fmt.Errorf("unsupported envelope version %d", start[len(start)-1])
+	}
+	header.Write(start)
+
+	algorithm, err := readField(r, header)
+	if err != nil {
+		return fmt.Errorf("reading envelope header: %w", err)
+	}
+
+	if string(algorithm) != EnvelopeAlgorithm {
+		return fmt.Errorf("unsupported envelope algorithm %q", algorithm)
+	}
+
+	keyID, err := readField(r, header)
+	if err != nil {
+		return fmt.Errorf("reading envelope header: %w", err)
+	}
+
+	if !bytes.Equal(keyID, public.KeyID()) {
+		log().Info("envelope is for another key", "key", fmt.Sprintf("%x", keyID))
+		return ErrWrongKey{}
+	}
+
+	encapsulated, err := readField(r, header)
+	if err != nil {
+		return fmt.Errorf("reading envelope header: %w", err)
+	}
+
+	var chunkSize uint32
+	if err := binary.Read(r, binary.BigEndian, &chunkSize); err != nil {
+		return fmt.Errorf("reading envelope header: %w", err)
+	}
+	binary.Write(header, binary.BigEndian, chunkSize)
+
+	if chunkSize == 0 || chunkSize > 16*1024*1024 {
+		return fmt.Errorf("invalid envelope chunk size %d", chunkSize)
+	}
+
+	// Decapsulate z
+	c := new(big.Int).SetBytes(encapsulated)
+	if len(encapsulated) != public.size() || c.Cmp(public.n) >= 0 {
+		return ErrDecryption{}
+	}
+
+	z, err := private.decrypt_block(c)
+	if err != nil {
+		return err
+	}
+
+	aead, err := public.envelopeAEAD(z)
+	if err != nil {
+		return err
+	}
+
+	// Open every chunk
+	br := bufio.NewReaderSize(r, int(chunkSize)+aead.Overhead())
+	chunk := make([]byte, int(chunkSize)+aead.Overhead())
+	opened := make([]byte, 0, chunkSize)
+
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		last := n < len(chunk)
+		if !last {
+			_, err := br.Peek(1)
+			if err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		opened, err = aead.Open(opened[:0], envelopeNonce(i, last), chunk[:n], header.Bytes())
+		if err != nil {
+			log().Warn("envelope chunk failed authentication", "chunk", i)
+			return ErrDecryption{}
+		}
+
+		if _, err := w.Write(opened); err != nil {
+			return err
+		}
+
+		if last {
+			log().Info("decrypted envelope", "key", fmt.Sprintf("%x", keyID), "chunks", i+1)
+			return nil
+		}
+
+		if i == ^uint32(0) {
+			return errors.New("too many chunks for one envelope")
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	private := testKey(t, 512)
+
+	sizes := []int{0, 1, 100, envelopeChunkSize - 1, envelopeChunkSize, envelopeChunkSize + 1, 3*envelopeChunkSize + 5}
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("Size: %d", size), func(t *testing.T) {
+			message := make([]byte, size)
+			rand.Read(message)
+
+			envelope := bytes.Buffer{}
+			if err := private.Public.EncryptEnvelope(bytes.NewReader(message), &envelope); err != nil {
+				t.Fatalf("Could not encrypt: %s", err)
+			}
+
+			if !IsEnvelope(envelope.Bytes()) {
+				t.Errorf("Envelope is not detected as an envelope")
+			}
+
+			plaintext := bytes.Buffer{}
+			if err := private.DecryptEnvelope(&envelope, &plaintext); err != nil {
+				t.Fatalf("Could not decrypt: %s", err)
+			}
+
+			if !bytes.Equal(plaintext.Bytes(), message) {
+				t.Errorf("Decrypted message does not match original")
+			}
+		})
+	}
+}
+
+// The envelope is only a little larger than the plaintext
+func TestEnvelopeOverhead(t *testing.T) {
+	private := testKey(t, 512)
+	message := make([]byte, 10*envelopeChunkSize)
+
+	envelope := bytes.Buffer{}
+	if err := private.Public.EncryptEnvelope(bytes.NewReader(message), &envelope); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	if overhead := envelope.Len() - len(message); overhead > 512 {
+		t.Errorf("Envelope adds %d bytes", overhead)
+	}
+}
+
+func TestEnvelopeWrongKey(t *testing.T) {
+	private := testKey(t, 512)
+	other := testKey(t, 512)
+
+	envelope := bytes.Buffer{}
+	if err := private.Public.EncryptEnvelope(bytes.NewReader([]byte("Hello World!")), &envelope); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	err := other.DecryptEnvelope(&envelope, &bytes.Buffer{})
+	if _, ok := err.(ErrWrongKey); !ok {
+		t.Errorf("Expected ErrWrongKey, got %v", err)
+	}
+}
+
+func TestEnvelopeTampered(t *testing.T) {
+	private := testKey(t, 512)
+	message := make([]byte, 2*envelopeChunkSize+10)
+
+	envelope := bytes.Buffer{}
+	if err := private.Public.EncryptEnvelope(bytes.NewReader(message), &envelope); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+	sealed := envelope.Bytes()
+
+	// Flip a bit in the header and in every chunk
+	for _, i := range []int{len(EnvelopeMagic) + 5, 200, 1000, envelopeChunkSize + 1000, len(sealed) - 1} {
+		tampered := append([]byte{}, sealed...)
+		tampered[i] ^= 0x01
+
+		if err := private.DecryptEnvelope(bytes.NewReader(tampered), &bytes.Buffer{}); err == nil {
+			t.Errorf("Decrypted an envelope with byte %d flipped", i)
+		}
+	}
+
+	// Cut the envelope off at the end of a chunk
+	headerLen := len(sealed) - len(message) - 3*16
+	truncated := sealed[:headerLen+envelopeChunkSize+16]
+	if err := private.DecryptEnvelope(bytes.NewReader(truncated), &bytes.Buffer{}); err == nil {
+		t.Errorf("Decrypted a truncated envelope")
+	}
+
+	// Extra data after the last chunk
+	extended := append(append([]byte{}, sealed...), 0)
+	if err := private.DecryptEnvelope(bytes.NewReader(extended), &bytes.Buffer{}); err == nil {
+		t.Errorf("Decrypted an extended envelope")
+	}
+}
+
+func TestIsEnvelope(t *testing.T) {
+	private := testKey(t, 128)
+
+	legacy := bytes.Buffer{}
+	private.Public.EncryptByByte(bytes.NewReader([]byte("Hello World!")), &legacy)
+
+	if IsEnvelope(legacy.Bytes()) {
+		t.Errorf("Legacy ciphertext detected as an envelope")
+	}
+}
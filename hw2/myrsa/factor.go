@@ -0,0 +1,123 @@
+package myrsa
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// FermatFactor factors n = a^2 - b^2 = (a - b)(a + b) by searching upwards from a = ⌈√n⌉.
+// It needs about (p - q)^2 / 8√n steps, so it's instant when p and q are close.
+func FermatFactor(public *PublicKey, steps int) (*PrivateKey, error) {
+	n := public.n
+
+	a := new(big.Int).Sqrt(n)
+	if new(big.Int).Mul(a, a).Cmp(n) < 0 {
+		a.Add(a, big.NewInt(1))
+	}
+
+	// b^2 = a^2 - n, and moving a to a + 1 adds 2a + 1
+	b2 := new(big.Int).Mul(a, a)
+	b2.Sub(b2, n)
+	b := new(big.Int)
+
+	for i := 0; i < steps; i++ {
+		if b.Sqrt(b2); new(big.Int).Mul(b, b).Cmp(b2) == 0 {
+			return recoverKey(public, new(big.Int).Sub(a, b))
+		}
+
+		b2.Add(b2, a).Add(b2, a).Add(b2, big.NewInt(1))
+		a.Add(a, big.NewInt(1))
+	}
+
+	return nil, fmt.Errorf("%w: p and q are not within %d steps of √n", ErrAttackFailed{}, steps)
+}
+
+// PollardPM1 finds a prime p where every prime power dividing p - 1 is at most bound.
+// a = 2^M mod n with M the product of those prime powers, then p divides gcd(a - 1, n).
+func PollardPM1(public *PublicKey, bound uint64) (*PrivateKey, error) {
+	n := public.n
+	one := big.NewInt(1)
+
+	a := big.NewInt(2)
+	x := new(big.Int)
+	for i, p := range numtheory.SmallPrimes(bound + 1) {
+		// The largest power of p at most bound
+		pk := p
+		for pk <= bound/p {
+			pk *= p
+		}
+		a.Exp(a, x.SetUint64(pk), n)
+
+		// Checking every so often is much cheaper than every time
+		if i%64 != 0 {
+			continue
+		}
+
+		if d := numtheory.GCD(x.Sub(a, one), n); d.Cmp(n) == 0 {
+			// Both p - 1 and q - 1 are smooth
+			break
+		} else if d.Cmp(one) != 0 {
+			return recoverKey(public, d)
+		}
+	}
+
+	if d := numtheory.GCD(x.Sub(a, one), n); d.Cmp(one) != 0 && d.Cmp(n) != 0 {
+		return recoverKey(public, d)
+	}
+
+	return nil, fmt.Errorf("%w: neither p - 1 nor q - 1 is %d-smooth", ErrAttackFailed{}, bound)
+}
+
+// PollardRho finds a small prime p with Brent's cycle detection on x -> x^2 + c mod n.
+// It needs about √p steps, so it only works when one of the primes is small.
+func PollardRho(public *PublicKey, steps int) (*PrivateKey, error) {
+	n := public.n
+	one := big.NewInt(1)
+
+	// Start over with another c if the cycle mod n closes at the same time as the one mod p
+	for c := int64(1); c < 10; c++ {
+		C := big.NewInt(c)
+		f := func(x *big.Int) *big.Int {
+			x.Mul(x, x).Add(x, C)
+			return x.Mod(x, n)
+		}
+
+		x, y := big.NewInt(2), big.NewInt(2)
+		product := big.NewInt(1)
+		diff := new(big.Int)
+		d := big.NewInt(1)
+
+		// y runs ahead of x by powers of two, gcds are batched over 256 steps
+		for power, i := 1, 0; i < steps && d.Cmp(one) == 0; power *= 2 {
+			x.Set(y)
+			for j := 0; j < power && i < steps; j++ {
+				f(y)
+				i++
+
+				product.Mul(product, diff.Sub(x, y).Abs(diff)).Mod(product, n)
+				if i%256 == 0 || j == power-1 {
+					if d = numtheory.GCD(product, n); d.Cmp(one) != 0 {
+						break
+					}
+				}
+			}
+		}
+
+		// The last batch
+		if d.Cmp(one) == 0 {
+			d = numtheory.GCD(product, n)
+		}
+
+		if d.Cmp(one) == 0 {
+			break
+		}
+
+		if d.Cmp(n) != 0 {
+			return recoverKey(public, d)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no factor found in %d steps", ErrAttackFailed{}, steps)
+}
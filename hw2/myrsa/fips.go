@@ -0,0 +1,157 @@
+package myrsa
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// PrimeMode chooses how KeygenWithOptions builds its primes.
+// Every mode keeps p and q at least √2·2^(bits-1) and, for keys big enough to allow it,
+// further apart than 2^(nlen/2 - 100) as FIPS 186-5 appendix A.1.3 asks.
+type PrimeMode int
+
+const (
+	// Any probable prime of the right size, the fastest
+	ProbablePrimes PrimeMode = iota
+
+	// Probable primes where p - 1 and p + 1 each have a large prime factor, FIPS 186-5 appendix A.1.5
+	StrongPrimes
+
+	// Primes built from smaller ones with Maurer's method, each with a Pocklington
+	// certificate that proves it is prime (see KeygenOptions.Certificate)
+	ProvablePrimes
+)
+
+func (mode PrimeMode) String() string {
+	switch mode {
+	case ProbablePrimes:
+		return "probable"
+	case StrongPrimes:
+		return "strong"
+	case ProvablePrimes:
+		return "provable"
+	default:
+		return "unknown"
+	}
+}
+
+// The size of the auxiliary primes of a strong prime, from FIPS 186-5 table A.1
+func auxiliaryBits(bits uint) uint {
+	switch {
+	case bits >= 2048:
+		return 201
+	case bits >= 1536:
+		return 171
+	case bits >= 1024:
+		return 141
+	default:
+		return bits / 4
+	}
+}
+
+// Whether two of the primes are close enough for Fermat factorization.
+// Pairs of 200 bits or less can't be 2^(bits/2 - 100) apart and are ignored.
+func fermatClose(primes []*big.Int) bool {
+	for i := range primes {
+		for j := i + 1; j < len(primes); j++ {
+			if primes[i].BitLen()+primes[j].BitLen() > 202 && tooClose(primes[i], primes[j]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Tests candidates one at a time, for the modes that build primes out of smaller ones
+type primeSearch struct {
+	ctx   context.Context
+	opts  *KeygenOptions
+	e     *big.Int
+	tries uint
+}
+
+// Counts a try and tests the candidate, e is nil for primes that don't go in the key
+func (s *primeSearch) try(candidate, e *big.Int) (bool, error) {
+	if err := s.ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.tries++
+	if s.opts.Progress != nil {
+		s.opts.Progress(s.tries)
+	}
+
+	if e == nil {
+		return s.opts.Test.probablyPrime(candidate), nil
+	}
+
+	return isPrimeCandidate(candidate, e, s.opts.Test), nil
+}
+
+// The first prime from a fresh sieve window
+func (s *primeSearch) auxiliary(bits uint) (*big.Int, error) {
+	source := newCandidates(s.opts.Random, bits)
+	for {
+		c, _, err := source.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, err := s.try(c, nil); err != nil || ok {
+			return c, err
+		}
+	}
+}
+
+// A prime p of size bits where p = 1 mod p1 and p = -1 mod p2 for auxiliary primes p1 and p2.
+// Follows FIPS 186-5 appendix A.1.5, starting over with new auxiliary primes when it fails.
+func (s *primeSearch) strongPrime(bits uint) (*big.Int, error) {
+	aux := auxiliaryBits(bits)
+
+	for {
+		p1, err := s.auxiliary(aux)
+		if err != nil {
+			return nil, err
+		}
+
+		p2, err := s.auxiliary(aux)
+		if err != nil {
+			return nil, err
+		}
+
+		// R = 1 mod 2p1 and R = -1 mod p2, which fails if they aren't coprime
+		twoP1 := new(big.Int).Lsh(p1, 1)
+		R, step, err := numtheory.CRT([]*big.Int{big.NewInt(1), big.NewInt(-1)}, []*big.Int{twoP1, p2})
+		if err != nil {
+			continue
+		}
+
+		// Give up on these auxiliary primes after 5 * bits candidates
+		for i := uint(0); i < 5*bits; {
+			X, err := primeCandidate(s.opts.Random, bits)
+			if err != nil {
+				return nil, err
+			}
+
+			// Y = X + ((R - X) mod 2p1p2) is the first number after X with the right residues
+			Y := new(big.Int).Sub(R, X)
+			Y.Mod(Y, step).Add(Y, X)
+
+			for ; i < 5*bits && Y.BitLen() == int(bits); i++ {
+				ok, err := s.try(Y, s.e)
+				if err != nil {
+					return nil, err
+				}
+
+				if ok {
+					return Y, nil
+				}
+
+				Y.Add(Y, step)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package myrsa
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+// p = 1 mod p1 and p = -1 mod p2 for the auxiliary primes drawn first from the same entropy
+func TestStrongPrime(t *testing.T) {
+	for _, bits := range []uint{256, 1024} {
+		opts := &KeygenOptions{Random: seededReader(int64(bits))}
+		search := &primeSearch{ctx: context.Background(), opts: opts, e: big.NewInt(65537)}
+
+		p, err := search.strongPrime(bits)
+		if err != nil {
+			t.Fatalf("Could not generate strong prime: %s", err)
+		}
+
+		if p.BitLen() != int(bits) || !p.ProbablyPrime(20) {
+			t.Fatalf("%s is not a %d bit prime", p, bits)
+		}
+
+		opts.Random = seededReader(int64(bits))
+		p1, _ := search.auxiliary(auxiliaryBits(bits))
+		p2, _ := search.auxiliary(auxiliaryBits(bits))
+
+		if p1.BitLen() != int(auxiliaryBits(bits)) || p2.BitLen() != int(auxiliaryBits(bits)) {
+			t.Errorf("Auxiliary primes have the wrong size")
+		}
+
+		if new(big.Int).Mod(p, p1).Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("p - 1 is not a multiple of p1")
+		}
+
+		if new(big.Int).Add(p, big.NewInt(1)).Mod(new(big.Int).Add(p, big.NewInt(1)), p2).Sign() != 0 {
+			t.Errorf("p + 1 is not a multiple of p2")
+		}
+	}
+}
+
+func TestKeygenModes(t *testing.T) {
+	for _, mode := range []PrimeMode{ProbablePrimes, StrongPrimes, ProvablePrimes} {
+		for _, count := range []int{2, 3} {
+			var certificates []*PrimeCertificate
+			private, err := KeygenWithOptions(context.Background(), KeygenOptions{
+				Bits:        1024,
+				Primes:      count,
+				Mode:        mode,
+				Certificate: func(cert *PrimeCertificate) { certificates = append(certificates, cert) },
+			})
+			if err != nil {
+				t.Fatalf("Could not generate %s key: %s", mode, err)
+			}
+
+			if report := private.Check(); len(report.Errors) != 0 || len(report.Warnings) != 0 {
+				t.Errorf("%s key has problems: %v", mode, report)
+			}
+
+			if mode != ProvablePrimes {
+				if len(certificates) != 0 {
+					t.Errorf("Got certificates for %s primes", mode)
+				}
+				continue
+			}
+
+			if len(certificates) != count {
+				t.Fatalf("Expected %d certificates, got %d", count, len(certificates))
+			}
+
+			for i, cert := range certificates {
+				if cert.Prime.Cmp(private.primes()[i]) != 0 {
+					t.Errorf("Certificate %d is for the wrong prime", i)
+				}
+
+				if err := cert.Verify(); err != nil {
+					t.Errorf("Certificate %d does not verify: %s", i, err)
+				}
+			}
+		}
+	}
+
+	if _, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 1024, Mode: ProvablePrimes + 1}); err == nil {
+		t.Errorf("Generated a key with an unknown prime mode")
+	}
+
+	if _, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 64, Mode: StrongPrimes}); err == nil {
+		t.Errorf("Generated strong primes too small for auxiliary primes")
+	}
+}
@@ -1,200 +1,387 @@
 package myrsa
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"math/big"
 	"runtime"
+	"sort"
 	"time"
+
+	"github.com/Alextopher/crypto/numtheory"
 )
 
-// For loop to find the gcd of two numbers
-func gcd(a, b *big.Int) *big.Int {
-	for b.Cmp(big.NewInt(0)) != 0 {
-		a, b = b, new(big.Int).Mod(a, b)
+// Reads a candidate prime of bits length from random
+func primeCandidate(random io.Reader, bits uint) (*big.Int, error) {
+	// Generate random bytes
+	b := make([]byte, (bits+7)/8)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return nil, err
+	}
+
+	// Clear the bits above bits
+	excess := 8*uint(len(b)) - bits
+	b[0] &= 0xff >> excess
+
+	// Set the top two bits so the product of two primes has exactly twice as many bits, and the last bit to 1
+	if excess < 7 {
+		b[0] |= 0xc0 >> excess
+	} else {
+		b[0] |= 0x01
+		b[1] |= 0x80
 	}
+	b[len(b)-1] |= 0x01
 
-	return a
+	return new(big.Int).SetBytes(b), nil
 }
 
-// Generates a uniform random number between a and b
-func randomBetween(a, b *big.Int) (*big.Int, error) {
-	// make sure a < b
-	if a.Cmp(b) > 0 {
-		a, b = b, a
+// Checks if a candidate is prime and p-1 is coprime to e, otherwise e has no inverse mod λ(n)
+func isPrimeCandidate(candidate, e *big.Int, test PrimalityTest) bool {
+	d := numtheory.GCD(new(big.Int).Sub(candidate, big.NewInt(1)), e)
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return false
 	}
 
-	// Calculate the range
-	r := new(big.Int).Sub(b, a)
+	return test.probablyPrime(candidate)
+}
+
+// KeygenOptions controls how KeygenWithOptions searches for primes
+type KeygenOptions struct {
+	// The size of the modulus in bits
+	Bits uint
+
+	// The number of primes, 2 if zero
+	Primes int
+
+	// Entropy for the prime candidates, crypto/rand.Reader if nil.
+	// Candidates are used in the order they are read, so a deterministic reader
+	// always gives the same key whatever the number of workers.
+	Random io.Reader
+
+	// The number of goroutines testing candidates, runtime.NumCPU() if zero
+	Workers int
 
-	// Generate a random number between 0 and r
-	n, err := rand.Int(rand.Reader, r)
+	// Called with the total number of candidates tried so far after each one is tested.
+	// Candidates with a small factor are removed by a sieve and never counted.
+	Progress func(tries uint)
 
-	// Add a to the number
-	n.Add(n, a)
+	// How candidates are tested, MillerRabin if zero
+	Test PrimalityTest
 
-	return n, err
+	// How the primes are built, ProbablePrimes if zero.
+	// StrongPrimes and ProvablePrimes test one candidate at a time and ignore Workers.
+	Mode PrimeMode
+
+	// With ProvablePrimes, called with the certificate of each prime of the key in order p, q, r3, ...
+	Certificate func(cert *PrimeCertificate)
+
+	// Where key generation events are logged, the logger from SetLogger if nil
+	Logger *slog.Logger
+}
+
+// A numbered candidate, and whether it was prime once tested
+type candidate struct {
+	index  uint
+	window uint
+	value  *big.Int
+	prime  bool
 }
 
-// Miller-Rabin primality test for n, with confidence k
-// Implemented using psuedo code from
-// https://en.wikipedia.org/wiki/Miller%E2%80%93Rabin_primality_test#Miller%E2%80%93Rabin_test
-func millerRabin(n *big.Int, k uint) bool {
-	// Pre calculate some important values
-	one := big.NewInt(1)
-	two := big.NewInt(2)
-	nm1 := new(big.Int).Sub(n, one)
-	nm2 := new(big.Int).Sub(n, two)
-
-	// write n as 2^r·d + 1 with d odd
-	d := new(big.Int).Set(nm1)
-	r := uint(0)
-	for d.Bit(0) == 0 {
-		d.Rsh(d, 1)
-		r++
-	}
-
-	// WitnessLoop
-WitnessLoop:
-	for i := uint(0); i < k; i++ {
-		// pick a random integer a in the range [2, n − 2]
-		a, _ := randomBetween(two, nm2)
-
-		// x ← a^d mod n
-		x := new(big.Int).Exp(a, d, n)
-
-		// if x = 1 or x = n − 1 then
-		if x.Cmp(one) == 0 || x.Cmp(nm1) == 0 {
-			continue
+// Finds count different primes of size bits, each from a different sieve window
+// Candidates are read one after another and tested by opts.Workers goroutines,
+// then taken in the order they were read so the result doesn't depend on scheduling.
+// tries is the number of candidates tried before this call and is returned updated.
+func randomPrimes(ctx context.Context, opts *KeygenOptions, bits uint, count int, e *big.Int, tries uint) ([]*big.Int, uint, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	candidates := make(chan candidate)
+	tested := make(chan candidate)
+	readErr := make(chan error, 1)
+
+	// Read candidates until cancelled
+	go func() {
+		defer close(candidates)
+
+		source := newCandidates(opts.Random, bits)
+		for i := uint(0); ; i++ {
+			value, window, err := source.next()
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			select {
+			case candidates <- candidate{index: i, window: window, value: value}:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		// repeat r − 1 times:
-		for j := uint(0); j < r-1; j++ {
-			// x ← x^2 mod n
-			x.Exp(x, two, n)
+	// Test them in parallel
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			for c := range candidates {
+				c.prime = isPrimeCandidate(c.value, e, opts.Test)
 
-			// if x = n − 1 then
-			if x.Cmp(nm1) == 0 {
-				continue WitnessLoop
+				select {
+				case tested <- c:
+				case <-ctx.Done():
+					return
+				}
 			}
+		}()
+	}
+
+	// Collect them in order
+	var found []*big.Int
+	used := map[uint]bool{}
+	pending := map[uint]candidate{}
+	next := uint(0)
+
+	for len(found) < count {
+		select {
+		case c := <-tested:
+			pending[c.index] = c
+		case err := <-readErr:
+			return nil, tries, err
+		case <-ctx.Done():
+			return nil, tries, ctx.Err()
 		}
 
-		// return “composite”
-		return false
+		for len(found) < count {
+			c, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			tries++
+			if opts.Progress != nil {
+				opts.Progress(tries)
+			}
+
+			if !c.prime || used[c.window] || containsPrime(found, c.value) {
+				continue
+			}
+
+			found = append(found, c.value)
+			used[c.window] = true
+			opts.Logger.Debug("found prime", "bits", bits, "tries", tries)
+		}
 	}
 
-	// return “probably prime”
-	return true
+	return found, tries, nil
 }
 
-// Tries to find a prime number of bits length. Returns nil if the attempt wasn't prime
-func randomPrimeOneShot(bits uint, coprime *big.Int) *big.Int {
-	// Generate random bytes
-	b := make([]byte, bits/8)
-	_, err := rand.Read(b)
-	if err != nil {
-		panic(err)
+// Generates primes of the given sizes with randomPrimes, grouped by size
+func probablePrimes(ctx context.Context, opts *KeygenOptions, sizes []uint, e *big.Int, tries uint) ([]*big.Int, uint, error) {
+	var primes []*big.Int
+	for i := 0; i < len(sizes); {
+		count := 1
+		for i+count < len(sizes) && sizes[i+count] == sizes[i] {
+			count++
+		}
+
+		found, total, err := randomPrimes(ctx, opts, sizes[i], count, e, tries)
+		if err != nil {
+			return nil, tries, err
+		}
+
+		primes = append(primes, found...)
+		tries = total
+		i += count
 	}
 
-	// Set first and last bits to 1
-	b[0] |= 0x80
-	b[len(b)-1] |= 0x01
+	return primes, tries, nil
+}
+
+// Generates strong or provable primes of the given sizes one at a time, with certificates by prime for ProvablePrimes
+func constructedPrimes(ctx context.Context, opts *KeygenOptions, sizes []uint, e *big.Int, tries uint) ([]*big.Int, map[string]*PrimeCertificate, uint, error) {
+	search := &primeSearch{ctx: ctx, opts: opts, e: e, tries: tries}
+	certificates := map[string]*PrimeCertificate{}
+
+	var primes []*big.Int
+	for len(primes) < len(sizes) {
+		bits := sizes[len(primes)]
 
-	// Create big int
-	i := new(big.Int)
-	i.SetBytes(b)
+		var prime *big.Int
+		if opts.Mode == StrongPrimes {
+			p, err := search.strongPrime(bits)
+			if err != nil {
+				return nil, nil, search.tries, err
+			}
+			prime = p
+		} else {
+			cert, err := search.provablePrime(bits, e)
+			if err != nil {
+				return nil, nil, search.tries, err
+			}
+			prime = cert.Prime
+			certificates[prime.String()] = cert
+		}
 
-	// Make sure the prime is coprime to our e
-	if coprime != nil {
-		d := gcd(i, coprime)
-		if d.Cmp(big.NewInt(1)) != 0 {
-			return nil
+		// Try again on the rare chance of a repeat
+		if !containsPrime(primes, prime) {
+			primes = append(primes, prime)
+			opts.Logger.Debug("found prime", "bits", bits, "mode", opts.Mode, "tries", search.tries)
 		}
 	}
 
-	// Check if it is prime
-	if millerRabin(i, WITNESS_COUNT) {
-		return i
+	return primes, certificates, search.tries, nil
+}
+
+// Whether x is one of the values
+func containsPrime(values []*big.Int, x *big.Int) bool {
+	for _, v := range values {
+		if v.Cmp(x) == 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	return nil
+// The product of the numbers
+func product(values []*big.Int) *big.Int {
+	result := big.NewInt(1)
+	for _, x := range values {
+		result.Mul(result, x)
+	}
+	return result
 }
 
-// Generates a random prime number of size bits that is coprime to e
-// Sends the prime to the channel c when it is found
-// Stops search when something is sent to the channel stop
-func randomPrime(bits uint, e *big.Int, primes chan<- *big.Int, stop chan uint) {
-	var count uint = 0
+// KeygenWithOptions generates a key with a modulus of opts.Bits bits made from opts.Primes primes.
+// The primes are as close in size as possible. It stops early with ctx.Err() if ctx is cancelled.
+func KeygenWithOptions(ctx context.Context, opts KeygenOptions) (*PrivateKey, error) {
+	if opts.Primes == 0 {
+		opts.Primes = 2
+	}
 
-	for {
-		select {
-		case <-stop:
-			stop <- count
-			return
-		default:
-			prime := randomPrimeOneShot(bits, e)
-			if prime != nil {
-				select {
-				case primes <- prime:
-					<-stop
-					stop <- count
-				case <-stop:
-					stop <- count
-				}
-			}
-			count++
+	if opts.Random == nil {
+		opts.Random = rand.Reader
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = log()
+	}
+
+	count := opts.Primes
+	if count < 2 || count > maxPrimes {
+		return nil, fmt.Errorf("a key needs between 2 and %d primes", maxPrimes)
+	}
+
+	if opts.Test != MillerRabin && opts.Test != BailliePSW {
+		return nil, fmt.Errorf("unknown primality test %d", opts.Test)
+	}
+
+	if opts.Bits/uint(count) < 16 {
+		return nil, errors.New("every prime must be at least 16 bits")
+	}
+
+	if opts.Mode < ProbablePrimes || opts.Mode > ProvablePrimes {
+		return nil, fmt.Errorf("unknown prime mode %d", opts.Mode)
+	}
+
+	if opts.Mode == StrongPrimes && opts.Bits/uint(count) < 64 {
+		return nil, errors.New("strong primes must be at least 64 bits")
+	}
+
+	// The first bits % count primes are one bit longer
+	sizes := make([]uint, count)
+	for i := range sizes {
+		sizes[i] = opts.Bits / uint(count)
+		if uint(i) < opts.Bits%uint(count) {
+			sizes[i]++
 		}
 	}
-}
 
-// RSA key genenerator
-func Keygen(keySize uint) *PrivateKey {
 	// Choose e
 	e := big.NewInt(65537)
 
-	// Generate p and q in parallel
+	// Generate the primes
 	start := time.Now()
-	primes := make(chan *big.Int)
-	stops := make([]chan uint, runtime.NumCPU())
-	for i := 0; i < runtime.NumCPU(); i++ {
-		stops[i] = make(chan uint)
-		go randomPrime(keySize, e, primes, stops[i])
-	}
+	var primes []*big.Int
+	var certificates map[string]*PrimeCertificate
+	tries := uint(0)
+	for {
+		var err error
+		if opts.Mode == ProbablePrimes {
+			primes, tries, err = probablePrimes(ctx, &opts, sizes, e, tries)
+		} else {
+			primes, certificates, tries, err = constructedPrimes(ctx, &opts, sizes, e, tries)
+		}
 
-	// Wait for the primes to be generated
-	p := <-primes
-	q := <-primes
+		if err != nil {
+			return nil, err
+		}
 
-	// Clean up the goroutines
-	total := uint(0)
-	for i := 0; i < runtime.NumCPU(); i++ {
-		stops[i] <- 0
-		total += <-stops[i]
+		// With more than two primes the product can come out a bit short, and
+		// primes close enough for Fermat factorization are thrown away, then start over
+		if product(primes).BitLen() != int(opts.Bits) {
+			opts.Logger.Debug("restarting key generation", "reason", "modulus too short", "bits", product(primes).BitLen())
+		} else if fermatClose(primes) {
+			opts.Logger.Debug("restarting key generation", "reason", "primes too close")
+		} else {
+			break
+		}
 	}
 
-	fmt.Println("Generated p and q in", time.Since(start), "after", total, "tries")
+	// Order the primes so p > q > r3 > ...
+	sort.Slice(primes, func(i, j int) bool {
+		return primes[i].Cmp(primes[j]) > 0
+	})
+
+	// n = p * q * ...
+	// phi(n) = (p-1) * (q-1) * ...
+	n := product(primes)
+	phi := big.NewInt(1)
+	for _, r := range primes {
+		phi.Mul(phi, new(big.Int).Sub(r, big.NewInt(1)))
+	}
 
-	// Order p and q
-	if p.Cmp(q) < 0 {
-		p, q = q, p
+	// Calculate d
+	d, err := numtheory.ModInverse(e, phi)
+	if err != nil {
+		return nil, err
 	}
 
-	// n = p * q
-	n := new(big.Int).Mul(p, q)
-	pm1 := new(big.Int).Sub(p, big.NewInt(1))
-	qm1 := new(big.Int).Sub(q, big.NewInt(1))
+	if opts.Mode == ProvablePrimes && opts.Certificate != nil {
+		for _, r := range primes {
+			opts.Certificate(certificates[r.String()])
+		}
+	}
 
-	// Calculate phi(n) = (p-1) * (q-1)
-	phi := new(big.Int).Mul(pm1, qm1)
+	opts.Logger.Info("generated key", "bits", opts.Bits, "primes", count, "mode", opts.Mode, "test", opts.Test,
+		"tries", tries, "duration", time.Since(start))
 
-	// Calculate d
-	_, _, d := pulverizer(phi, e)
-	d.Mod(d, phi)
+	return newPrivateKey(n, e, d, primes), nil
+}
+
+// RSA key genenerator, p and q are each keySize bits
+func Keygen(keySize uint) (*PrivateKey, error) {
+	if keySize < 16 || keySize%8 != 0 {
+		return nil, errors.New("key size must be a multiple of 8 and at least 16 bits")
+	}
+
+	return KeygenWithOptions(context.Background(), KeygenOptions{Bits: 2 * keySize})
+}
 
-	public := &PublicKey{
-		n: n,
-		e: e,
+// KeygenMultiPrime generates a key with a modulus of bits bits made from count primes (RFC 8017 section 3.2)
+// The primes are as close in size as possible, count = 2 is a normal RSA key
+func KeygenMultiPrime(bits uint, count int) (*PrivateKey, error) {
+	if count < 2 {
+		return nil, fmt.Errorf("a key needs between 2 and %d primes", maxPrimes)
 	}
 
-	return &PrivateKey{public, d, p, q, nil, nil}
+	return KeygenWithOptions(context.Background(), KeygenOptions{Bits: bits, Primes: count})
 }
@@ -0,0 +1,110 @@
+package myrsa
+
+import (
+	"context"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"testing"
+	"time"
+)
+
+// A deterministic entropy source
+func seededReader(seed int64) io.Reader {
+	return mathrand.New(mathrand.NewSource(seed))
+}
+
+// The same seed always gives the same key, however many workers test candidates
+func TestKeygenDeterministic(t *testing.T) {
+	var keys []*PrivateKey
+	for _, workers := range []int{1, 2, 4, 1} {
+		private, err := KeygenWithOptions(context.Background(), KeygenOptions{
+			Bits:    512,
+			Random:  seededReader(42),
+			Workers: workers,
+		})
+		if err != nil {
+			t.Fatalf("Could not generate key: %s", err)
+		}
+
+		if err := private.Validate(); err != nil {
+			t.Errorf("Key is invalid: %s", err)
+		}
+
+		keys = append(keys, private)
+	}
+
+	for _, private := range keys[1:] {
+		sameKey(t, keys[0], private)
+	}
+
+	other, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 512, Random: seededReader(43)})
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	if other.Public.n.Cmp(keys[0].Public.n) == 0 {
+		t.Errorf("Different seeds gave the same key")
+	}
+}
+
+func TestKeygenProgress(t *testing.T) {
+	var reports []uint
+	_, err := KeygenWithOptions(context.Background(), KeygenOptions{
+		Bits:     512,
+		Primes:   3,
+		Progress: func(tries uint) { reports = append(reports, tries) },
+	})
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	if len(reports) < 3 {
+		t.Fatalf("Expected at least 3 progress reports, got %d", len(reports))
+	}
+
+	for i, tries := range reports {
+		if tries != uint(i+1) {
+			t.Fatalf("Progress report %d said %d tries", i, tries)
+		}
+	}
+}
+
+func TestKeygenCancel(t *testing.T) {
+	// Already cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := KeygenWithOptions(ctx, KeygenOptions{Bits: 512}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Cancelled while searching for huge primes
+	ctx, cancel = context.WithCancel(context.Background())
+	start := time.Now()
+	_, err := KeygenWithOptions(ctx, KeygenOptions{
+		Bits: 16384,
+		Progress: func(tries uint) {
+			if tries == 5 {
+				cancel()
+			}
+		},
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Cancelling took %s", elapsed)
+	}
+}
+
+// Running out of entropy is an error, not a panic or a hang
+func TestKeygenEntropyError(t *testing.T) {
+	short := io.LimitReader(seededReader(1), 100)
+
+	if _, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 1024, Random: short}); err == nil {
+		t.Errorf("Generated a key from 100 bytes of entropy")
+	}
+}
@@ -2,8 +2,10 @@ package myrsa
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"math/big"
+	"strings"
 )
 
 type PrivateKey struct {
@@ -13,12 +15,14 @@ type PrivateKey struct {
 	// The private exponent
 	d *big.Int
 
-	// The primes
+	// The primes, p > q
 	p, q *big.Int
 
-	// Precomputed values for faster decryption
-	ap *big.Int
-	aq *big.Int
+	// Any further primes of a multi-prime key (RFC 8017 section 3.2)
+	additional []*big.Int
+
+	// Precomputed values for faster decryption, one for each prime
+	coefficients []*big.Int
 }
 
 type PublicKey struct {
@@ -29,69 +33,187 @@ type PublicKey struct {
 	e *big.Int
 }
 
-// Save the private key to the writer
-func (private *PrivateKey) Save(w io.Writer) {
-	// Save the public key first
-	private.Public.Save(w)
+// ErrMalformedKey
+// Error returned when a key file can't be parsed or holds values that can't be part of a key
+type ErrMalformedKey struct{}
 
-	// Save the private exponent
-	w.Write([]byte(private.d.String()))
-	w.Write([]byte("\n"))
+func (e ErrMalformedKey) Error() string {
+	return "malformed RSA key"
+}
 
-	// Save the primes
-	w.Write([]byte(private.p.String()))
-	w.Write([]byte("\n"))
+// ErrInconsistentKey
+// Error returned when the values of a private key don't belong together, for example n != p * q
+type ErrInconsistentKey struct{}
 
-	w.Write([]byte(private.q.String()))
-	w.Write([]byte("\n"))
+func (e ErrInconsistentKey) Error() string {
+	return "inconsistent RSA key parameters"
 }
 
-func (public *PublicKey) Save(w io.Writer) {
-	// First line is the modulus
-	w.Write([]byte(public.n.String()))
-	w.Write([]byte("\n"))
+// Checks the values of a public key are usable
+func checkPublicKey(n, e *big.Int) error {
+	if n == nil || e == nil || n.Sign() <= 0 || e.Sign() <= 0 {
+		return fmt.Errorf("%w: modulus and exponent must be positive", ErrMalformedKey{})
+	}
+
+	if e.Cmp(big.NewInt(1)) <= 0 || e.Cmp(n) >= 0 {
+		return fmt.Errorf("%w: public exponent out of range", ErrMalformedKey{})
+	}
 
-	// Second line is the public exponent
-	w.Write([]byte(public.e.String()))
-	w.Write([]byte("\n"))
+	return nil
 }
 
-func ReadPublicKey(r io.Reader) *PublicKey {
-	scanner := bufio.NewScanner(r)
+// The most primes a key may have
+const maxPrimes = 16
+
+// Checks the values of a private key could be part of a key, Validate checks they belong together
+func checkPrivateKey(n, e, d *big.Int, primes []*big.Int) error {
+	if err := checkPublicKey(n, e); err != nil {
+		return err
+	}
+
+	if len(primes) < 2 || len(primes) > maxPrimes {
+		return fmt.Errorf("%w: a key needs between 2 and %d primes", ErrMalformedKey{}, maxPrimes)
+	}
+
+	one := big.NewInt(1)
+	for _, x := range append([]*big.Int{d}, primes...) {
+		if x == nil || x.Cmp(one) <= 0 {
+			return fmt.Errorf("%w: private values must be greater than 1", ErrMalformedKey{})
+		}
+	}
+
+	return nil
+}
+
+// Builds a private key from its primes, the first two become p > q
+func newPrivateKey(n, e, d *big.Int, primes []*big.Int) *PrivateKey {
+	p, q := primes[0], primes[1]
+	if p.Cmp(q) < 0 {
+		p, q = q, p
+	}
+
+	return &PrivateKey{
+		Public:     &PublicKey{n, e},
+		d:          d,
+		p:          p,
+		q:          q,
+		additional: primes[2:],
+	}
+}
+
+// All of the primes, starting with p and q
+func (private *PrivateKey) primes() []*big.Int {
+	return append([]*big.Int{private.p, private.q}, private.additional...)
+}
 
-	// First line is the modulus
-	scanner.Scan()
-	n, _ := new(big.Int).SetString(scanner.Text(), 10)
+// BitLen returns the size of the modulus in bits
+func (public *PublicKey) BitLen() int {
+	return public.n.BitLen()
+}
 
-	// Second line is the public exponent
-	scanner.Scan()
-	e, _ := new(big.Int).SetString(scanner.Text(), 10)
+// Exponent returns a copy of the public exponent e
+func (public *PublicKey) Exponent() *big.Int {
+	return new(big.Int).Set(public.e)
+}
 
-	return &PublicKey{n, e}
+// PrimeCount returns the number of primes in the modulus, 2 unless it is a multi-prime key
+func (private *PrivateKey) PrimeCount() int {
+	return 2 + len(private.additional)
 }
 
-func ReadPrivateKey(r io.Reader) *PrivateKey {
+// Writes each number on its own line
+func writeLines(w io.Writer, values ...*big.Int) error {
+	for _, x := range values {
+		if _, err := io.WriteString(w, x.String()+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reads between min and max decimal numbers, one per line
+// After the first min lines reading stops at the end of the input or an empty line
+func readLines(r io.Reader, min, max int) ([]*big.Int, error) {
 	scanner := bufio.NewScanner(r)
+	values := make([]*big.Int, 0, min)
+
+	for len(values) < max {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" && len(values) >= min {
+			break
+		}
+
+		x, ok := new(big.Int).SetString(line, 10)
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d is not a number", ErrMalformedKey{}, len(values)+1)
+		}
+		values = append(values, x)
+	}
+
+	if len(values) < min {
+		return nil, fmt.Errorf("%w: expected %d lines, got %d", ErrMalformedKey{}, min, len(values))
+	}
+
+	return values, nil
+}
 
-	// First line is the modulus
-	scanner.Scan()
-	n, _ := new(big.Int).SetString(scanner.Text(), 10)
+// Save the private key to the writer
+// The lines are n, e, d, p and q, followed by any additional primes
+func (private *PrivateKey) Save(w io.Writer) error {
+	return writeLines(w, append([]*big.Int{private.Public.n, private.Public.e, private.d}, private.primes()...)...)
+}
+
+// Save the public key to the writer
+// The first line is the modulus and the second is the public exponent
+func (public *PublicKey) Save(w io.Writer) error {
+	return writeLines(w, public.n, public.e)
+}
 
-	// Second line is the public exponent
-	scanner.Scan()
-	e, _ := new(big.Int).SetString(scanner.Text(), 10)
+// ReadPublicKey reads a public key written by PublicKey.Save
+func ReadPublicKey(r io.Reader) (*PublicKey, error) {
+	values, err := readLines(r, 2, 2)
+	if err != nil {
+		return nil, err
+	}
 
-	// Second line is the private exponent
-	scanner.Scan()
-	d, _ := new(big.Int).SetString(scanner.Text(), 10)
+	n, e := values[0], values[1]
+	if err := checkPublicKey(n, e); err != nil {
+		return nil, err
+	}
 
-	// Third line is the p
-	scanner.Scan()
-	p, _ := new(big.Int).SetString(scanner.Text(), 10)
+	return &PublicKey{n, e}, nil
+}
 
-	// Fourth line is the q
-	scanner.Scan()
-	q, _ := new(big.Int).SetString(scanner.Text(), 10)
+// ReadPrivateKey reads a private key written by PrivateKey.Save and validates it
+func ReadPrivateKey(r io.Reader) (*PrivateKey, error) {
+	return readPrivateKey(r, true)
+}
 
-	return &PrivateKey{&PublicKey{n, e}, d, p, q, nil, nil}
+func readPrivateKey(r io.Reader, validate bool) (*PrivateKey, error) {
+	values, err := readLines(r, 5, 3+maxPrimes)
+	if err != nil {
+		return nil, err
+	}
+
+	n, e, d, primes := values[0], values[1], values[2], values[3:]
+	if err := checkPrivateKey(n, e, d, primes); err != nil {
+		return nil, err
+	}
+
+	private := newPrivateKey(n, e, d, primes)
+	if validate {
+		if err := private.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return private, nil
 }
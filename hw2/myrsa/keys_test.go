@@ -0,0 +1,199 @@
+package myrsa
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// A writer that always fails
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+// Writes the values in the legacy key format
+func lines(values ...*big.Int) string {
+	b := strings.Builder{}
+	for _, v := range values {
+		b.WriteString(v.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestReadKeyErrors(t *testing.T) {
+	private := testKey(t, 128)
+	n, e, d, p, q := private.Public.n, private.Public.e, private.d, private.p, private.q
+	one := big.NewInt(1)
+
+	malformed := []string{
+		"",
+		"123\n",
+		"not a number\n65537\n",
+		lines(n, e, d, p),
+		lines(n, e, d, p) + "q\n",
+		lines(n, big.NewInt(-3), d, p, q),
+		lines(n, n, d, p, q),
+		lines(n, e, d, one, q),
+	}
+
+	for _, input := range malformed {
+		_, err := ReadPrivateKey(strings.NewReader(input))
+		if !errors.As(err, &ErrMalformedKey{}) {
+			t.Errorf("Expected ErrMalformedKey for %q, got %v", input, err)
+		}
+	}
+
+	inconsistent := []string{
+		lines(new(big.Int).Add(n, big.NewInt(2)), e, d, p, q),
+		lines(n, e, new(big.Int).Add(d, one), p, q),
+		lines(n, big.NewInt(3), d, p, q),
+	}
+
+	for _, input := range inconsistent {
+		_, err := ReadPrivateKey(strings.NewReader(input))
+		if !errors.As(err, &ErrInconsistentKey{}) {
+			t.Errorf("Expected ErrInconsistentKey for %q, got %v", input, err)
+		}
+	}
+
+	for _, input := range []string{"", "123\n", "123\nabc\n", "0\n3\n"} {
+		_, err := ReadPublicKey(strings.NewReader(input))
+		if !errors.As(err, &ErrMalformedKey{}) {
+			t.Errorf("Expected ErrMalformedKey for %q, got %v", input, err)
+		}
+	}
+}
+
+// The legacy format is read with p and q in either order
+func TestReadPrivateKeySwapsPrimes(t *testing.T) {
+	private := testKey(t, 128)
+	input := lines(private.Public.n, private.Public.e, private.d, private.q, private.p)
+
+	key, err := ReadPrivateKey(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Could not read private key: %s", err)
+	}
+
+	if key.p.Cmp(private.p) != 0 || key.q.Cmp(private.q) != 0 {
+		t.Errorf("Primes were not reordered")
+	}
+}
+
+func TestEncryptErrors(t *testing.T) {
+	// A modulus smaller than a byte can't hold every message
+	tiny := &PublicKey{big.NewInt(253), big.NewInt(3)}
+	err := tiny.EncryptByByte(bytes.NewReader([]byte{0xff}), &bytes.Buffer{})
+	if _, ok := err.(ErrMessageTooLong); !ok {
+		t.Errorf("Expected ErrMessageTooLong, got %v", err)
+	}
+
+	private := testKey(t, 128)
+	if err := private.Public.Encrypt(strings.NewReader("Hello World!"), failingWriter{}); err == nil {
+		t.Errorf("Encrypt did not return the write error")
+	}
+
+	if err := private.Public.EncryptByByte(strings.NewReader("Hello World!"), failingWriter{}); err == nil {
+		t.Errorf("EncryptByByte did not return the write error")
+	}
+
+	if err := private.Save(failingWriter{}); err == nil {
+		t.Errorf("Save did not return the write error")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	private := testKey(t, 128)
+
+	inputs := []string{
+		"not a number\n",
+		"-5\n",
+		private.Public.n.String() + "\n",
+	}
+
+	for _, input := range inputs {
+		err := private.Decrypt(strings.NewReader(input), &bytes.Buffer{})
+		if !errors.As(err, &ErrDecryption{}) {
+			t.Errorf("Expected ErrDecryption for %q, got %v", input, err)
+		}
+	}
+
+	ciphertext := bytes.Buffer{}
+	if err := private.Public.Encrypt(strings.NewReader("Hello World!"), &ciphertext); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	if err := private.Decrypt(&ciphertext, failingWriter{}); err == nil {
+		t.Errorf("Decrypt did not return the write error")
+	}
+}
+
+// Adds a key in every format to the fuzzing corpus
+func addKeySeeds(f *testing.F) {
+	private := testKey(f, 128)
+
+	legacy := bytes.Buffer{}
+	private.Save(&legacy)
+	f.Add(legacy.Bytes())
+
+	legacy.Reset()
+	private.Public.Save(&legacy)
+	f.Add(legacy.Bytes())
+
+	pem := bytes.Buffer{}
+	private.SavePEM(&pem)
+	f.Add(pem.Bytes())
+
+	pem.Reset()
+	private.Public.SavePEM(&pem)
+	f.Add(pem.Bytes())
+
+	f.Add([]byte("3\n\n\n"))
+}
+
+// Keys that parse must be usable
+func FuzzParsePrivateKey(f *testing.F) {
+	addKeySeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		private, err := ParsePrivateKey(data)
+		if err != nil {
+			return
+		}
+
+		if err := private.Validate(); err != nil {
+			t.Fatalf("Parsed an invalid key: %s", err)
+		}
+
+		if private.p.Cmp(private.q) < 0 {
+			t.Fatalf("Parsed a key with p < q")
+		}
+
+		// It survives a round trip through the legacy format
+		buf := bytes.Buffer{}
+		private.Save(&buf)
+		if _, err := ReadPrivateKey(&buf); err != nil {
+			t.Fatalf("Could not read a saved key: %s", err)
+		}
+	})
+}
+
+func FuzzParsePublicKey(f *testing.F) {
+	addKeySeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		public, err := ParsePublicKey(data)
+		if err != nil {
+			return
+		}
+
+		if err := checkPublicKey(public.n, public.e); err != nil {
+			t.Fatalf("Parsed an invalid key: %s", err)
+		}
+	})
+}
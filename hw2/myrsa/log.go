@@ -0,0 +1,38 @@
+package myrsa
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// The package is silent unless SetLogger is called
+var logger atomic.Pointer[slog.Logger]
+
+// Drops every record without formatting it
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discard = slog.New(discardHandler{})
+
+// SetLogger sends key generation and decryption events to l, nil turns logging off again.
+// Nothing secret is logged: no keys, plaintext or reasons a padded ciphertext was rejected.
+//
+//	Debug  every prime found and every restart of key generation
+//	Info   each key generated and each file decrypted
+//	Warn   faults caught by checking a CRT result
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// The logger set by SetLogger, or one that drops everything
+func log() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return discard
+}
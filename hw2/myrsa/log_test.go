@@ -0,0 +1,84 @@
+package myrsa
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Collects everything the package logs until the test ends
+func captureLog(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	SetLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { SetLogger(nil) })
+	return buf
+}
+
+func TestLogKeygenAndDecrypt(t *testing.T) {
+	buf := captureLog(t)
+
+	private, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 512})
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	ciphertext := bytes.Buffer{}
+	if err := private.Public.EncryptEncoded(strings.NewReader("Hello World!"), &ciphertext, Hex); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	if err := private.Decrypt(&ciphertext, io.Discard); err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+
+	envelope := bytes.Buffer{}
+	if err := private.Public.EncryptEnvelope(strings.NewReader("Hello World!"), &envelope); err != nil {
+		t.Fatalf("Could not encrypt envelope: %s", err)
+	}
+
+	if err := private.DecryptEnvelope(&envelope, io.Discard); err != nil {
+		t.Fatalf("Could not decrypt envelope: %s", err)
+	}
+
+	logged := buf.String()
+	for _, event := range []string{`"found prime"`, `"generated key" bits=512 primes=2 mode=probable test=Miller-Rabin`, `"decrypted blocks" encoding=hex framed=true blocks=1`, `"decrypted envelope"`} {
+		if !strings.Contains(logged, event) {
+			t.Errorf("Expected %s in the log:\n%s", event, logged)
+		}
+	}
+
+	// None of the secrets
+	for _, secret := range append([]*big.Int{private.d}, private.primes()...) {
+		if strings.Contains(logged, secret.String()) || strings.Contains(logged, secret.Text(16)) {
+			t.Errorf("The log contains part of the private key")
+		}
+	}
+
+	if strings.Contains(logged, "Hello World!") {
+		t.Errorf("The log contains the plaintext")
+	}
+}
+
+// KeygenOptions.Logger takes precedence, and nothing is logged after SetLogger(nil)
+func TestLoggerOverride(t *testing.T) {
+	global := captureLog(t)
+	SetLogger(nil)
+
+	local := &bytes.Buffer{}
+	_, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 256, Logger: slog.New(slog.NewTextHandler(local, nil))})
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	if !strings.Contains(local.String(), "generated key") {
+		t.Errorf("KeygenOptions.Logger was not used")
+	}
+
+	if global.Len() != 0 {
+		t.Errorf("Logged after SetLogger(nil): %s", global.String())
+	}
+}
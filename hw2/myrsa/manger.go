@@ -0,0 +1,108 @@
+package myrsa
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// PaddingOracle answers whether a ciphertext decrypts to a number below 2^(8(k-1)),
+// in other words whether the first byte of the encoded message is zero.
+// DecryptOAEP doesn't leak this, but an implementation that checks the first byte
+// on its own and fails differently or faster when it is wrong does.
+type PaddingOracle func(c *big.Int) bool
+
+// Manger recovers the encoded message m = c^d mod n from an OAEP ciphertext with a padding oracle,
+// following "A Chosen Ciphertext Attack on RSA OAEP" (Manger, 2001). Like Bleichenbacher's attack on
+// PKCS #1 v1.5 it multiplies the message by chosen f through c·f^e and narrows down m from the answers.
+// It takes about 8k oracle queries for a k byte modulus, the message can then be unmasked without the key.
+func Manger(public *PublicKey, ciphertext []byte, oracle PaddingOracle) ([]byte, error) {
+	n, e := public.n, public.e
+	k := public.size()
+
+	c := new(big.Int).SetBytes(ciphertext)
+	if len(ciphertext) != k || c.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("%w: the ciphertext is not %d bytes", ErrAttackFailed{}, k)
+	}
+
+	// B = 2^(8(k-1)), the attack needs 2B < n
+	B := new(big.Int).Lsh(big.NewInt(1), uint(8*(k-1)))
+	twoB := new(big.Int).Lsh(B, 1)
+	if twoB.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("%w: the modulus is too close to a power of 256", ErrAttackFailed{})
+	}
+
+	// Whether f·m mod n < B
+	queries := 0
+	below := func(f *big.Int) bool {
+		queries++
+		fe := new(big.Int).Exp(f, e, n)
+		return oracle(fe.Mul(fe, c).Mod(fe, n))
+	}
+
+	if !below(big.NewInt(1)) {
+		return nil, fmt.Errorf("%w: the oracle rejects the ciphertext itself", ErrAttackFailed{})
+	}
+
+	// Step 1: double f1 until f1·m is in [B, 2B), then f1/2·m is in [B/2, B)
+	f1 := big.NewInt(2)
+	for below(f1) {
+		f1.Lsh(f1, 1)
+
+		if f1.BitLen() > 8*k {
+			return nil, fmt.Errorf("%w: the oracle is inconsistent", ErrAttackFailed{})
+		}
+	}
+	half := new(big.Int).Rsh(f1, 1)
+
+	// Step 2: step f2 by f1/2 from ⌊(n+B)/B⌋·f1/2 until f2·m wraps around n into [n, n+B)
+	nB := new(big.Int).Add(n, B)
+	f2 := new(big.Int).Quo(nB, B)
+	f2.Mul(f2, half)
+	for !below(f2) {
+		f2.Add(f2, half)
+
+		if queries > 16*k*8 {
+			return nil, fmt.Errorf("%w: the oracle is inconsistent", ErrAttackFailed{})
+		}
+	}
+
+	// Step 3: m is in [⌈n/f2⌉, ⌊(n+B)/f2⌋], halve the interval with every query
+	min := ceilQuo(n, f2)
+	max := new(big.Int).Quo(nB, f2)
+	for min.Cmp(max) < 0 {
+		// f is chosen so f·m lands near i·n + B for the i where f·[min, max] spans about 2B
+		width := new(big.Int).Sub(max, min)
+		ftmp := new(big.Int).Quo(twoB, width)
+		i := new(big.Int).Mul(ftmp, min)
+		i.Quo(i, n)
+		in := new(big.Int).Mul(i, n)
+		f3 := ceilQuo(in, min)
+
+		inB := in.Add(in, B)
+		if below(f3) {
+			max = new(big.Int).Quo(inB, f3)
+		} else {
+			min = ceilQuo(inB, f3)
+		}
+
+		if min.Cmp(max) > 0 || queries > 16*k*8 {
+			return nil, fmt.Errorf("%w: the oracle is inconsistent", ErrAttackFailed{})
+		}
+	}
+
+	// Check the answer with the public key
+	if new(big.Int).Exp(min, e, n).Cmp(c) != 0 {
+		return nil, fmt.Errorf("%w: the oracle is inconsistent", ErrAttackFailed{})
+	}
+
+	return min.FillBytes(make([]byte, k)), nil
+}
+
+// ⌈a / b⌉ for positive a and b
+func ceilQuo(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
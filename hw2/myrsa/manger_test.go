@@ -0,0 +1,121 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"math/big"
+	"testing"
+)
+
+// The oracle a careless OAEP implementation exposes by checking the first byte before anything else
+func leakyOracle(private *PrivateKey) PaddingOracle {
+	B := new(big.Int).Lsh(big.NewInt(1), uint(8*(private.Public.size()-1)))
+
+	return func(c *big.Int) bool {
+		m, err := private.decrypt_block(c)
+		return err == nil && m.Cmp(B) < 0
+	}
+}
+
+// Removes the OAEP masks from an encoded message, which only takes the hash
+func unmaskOAEP(t *testing.T, em []byte, hash hash.Hash) []byte {
+	t.Helper()
+
+	hLen := hash.Size()
+	seed := append([]byte{}, em[1:1+hLen]...)
+	db := append([]byte{}, em[1+hLen:]...)
+
+	mgf1XOR(seed, hash, db)
+	mgf1XOR(db, hash, seed)
+
+	i := bytes.IndexByte(db[hLen:], 0x01)
+	if i < 0 {
+		t.Fatalf("Recovered encoded message has no 0x01 separator")
+	}
+	return db[hLen+i+1:]
+}
+
+func TestManger(t *testing.T) {
+	private := testKey(t, 512)
+
+	ciphertext, err := private.Public.EncryptOAEP(sha256.New(), rand.Reader, []byte("Hello World!"), nil)
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	queries := 0
+	leaky := leakyOracle(private)
+	oracle := func(c *big.Int) bool {
+		queries++
+		return leaky(c)
+	}
+
+	em, err := Manger(private.Public, ciphertext, oracle)
+	if err != nil {
+		t.Fatalf("Manger's attack failed: %s", err)
+	}
+
+	if msg := unmaskOAEP(t, em, sha256.New()); string(msg) != "Hello World!" {
+		t.Errorf("Manger's attack recovered %q", msg)
+	}
+
+	// About 8k queries for step 3, plus a few hundred for steps 1 and 2
+	if limit := 8*private.Public.size() + 512; queries > limit {
+		t.Errorf("Manger's attack took %d queries, expected at most %d", queries, limit)
+	}
+}
+
+// An oracle that lies can't give a wrong plaintext
+func TestMangerInconsistentOracle(t *testing.T) {
+	private := testKey(t, 512)
+
+	ciphertext, err := private.Public.EncryptOAEP(sha256.New(), rand.Reader, []byte("Hello World!"), nil)
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	leaky := leakyOracle(private)
+	queries := 0
+	oracle := func(c *big.Int) bool {
+		queries++
+		return leaky(c) != (queries%37 == 0)
+	}
+
+	_, err = Manger(private.Public, ciphertext, oracle)
+	if !errors.As(err, &ErrAttackFailed{}) {
+		t.Errorf("Expected ErrAttackFailed, got %v", err)
+	}
+}
+
+// DecryptOAEP fails the same way whether or not the first byte is zero, so it isn't an oracle
+func TestDecryptOAEPDoesNotLeak(t *testing.T) {
+	private := testKey(t, 512)
+	leaky := leakyOracle(private)
+	n := private.Public.n
+
+	var zero, nonzero error
+	for zero == nil || nonzero == nil {
+		c, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			t.Fatalf("Could not generate ciphertext: %s", err)
+		}
+
+		_, err = private.DecryptOAEP(sha256.New(), c.FillBytes(make([]byte, private.Public.size())), nil)
+		if err == nil {
+			t.Fatalf("Decrypted a random ciphertext")
+		}
+
+		if leaky(c) {
+			zero = err
+		} else {
+			nonzero = err
+		}
+	}
+
+	if zero != nonzero {
+		t.Errorf("DecryptOAEP returned %v for a zero first byte and %v otherwise", zero, nonzero)
+	}
+}
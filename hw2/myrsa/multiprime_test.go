@@ -0,0 +1,185 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Generates a multi-prime key or fails the test
+func testMultiPrimeKey(t testing.TB, bits uint, count int) *PrivateKey {
+	t.Helper()
+
+	private, err := KeygenMultiPrime(bits, count)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	return private
+}
+
+func TestKeygenMultiPrime(t *testing.T) {
+	for _, count := range []int{2, 3, 4, 5} {
+		for _, bits := range []uint{1024, 1030} {
+			t.Run(fmt.Sprintf("%d bits %d primes", bits, count), func(t *testing.T) {
+				private := testMultiPrimeKey(t, bits, count)
+
+				if private.Public.n.BitLen() != int(bits) {
+					t.Errorf("Modulus is %d bits", private.Public.n.BitLen())
+				}
+
+				if len(private.primes()) != count {
+					t.Errorf("Key has %d primes", len(private.primes()))
+				}
+
+				if err := private.Validate(); err != nil {
+					t.Errorf("Key is invalid: %s", err)
+				}
+
+				// Every private key operation goes through the CRT with all of the primes
+				ciphertext, err := private.Public.EncryptOAEP(sha256.New(), rand.Reader, []byte("Hello World!"), nil)
+				if err != nil {
+					t.Fatalf("Could not encrypt: %s", err)
+				}
+
+				plaintext, err := private.DecryptOAEP(sha256.New(), ciphertext, nil)
+				if err != nil {
+					t.Fatalf("Could not decrypt: %s", err)
+				}
+
+				if string(plaintext) != "Hello World!" {
+					t.Errorf("Decrypted message does not match original")
+				}
+
+				d := digest(crypto.SHA256, "Hello World!")
+				sig, err := private.SignPKCS1v15(crypto.SHA256, d)
+				if err != nil {
+					t.Fatalf("Could not sign: %s", err)
+				}
+
+				if err := private.Public.VerifyPKCS1v15(crypto.SHA256, d, sig); err != nil {
+					t.Errorf("Signature did not verify: %s", err)
+				}
+			})
+		}
+	}
+}
+
+func TestKeygenMultiPrimeRejects(t *testing.T) {
+	for _, count := range []int{-1, 0, 1, maxPrimes + 1} {
+		if _, err := KeygenMultiPrime(1024, count); err == nil {
+			t.Errorf("Generated a key with %d primes", count)
+		}
+	}
+
+	if _, err := KeygenMultiPrime(60, 4); err == nil {
+		t.Errorf("Generated a key with 15 bit primes")
+	}
+}
+
+func TestMultiPrimeStorage(t *testing.T) {
+	private := testMultiPrimeKey(t, 1024, 3)
+
+	// Legacy format
+	buf := bytes.Buffer{}
+	if err := private.Save(&buf); err != nil {
+		t.Fatalf("Could not save: %s", err)
+	}
+
+	parsed, err := ReadPrivateKey(&buf)
+	if err != nil {
+		t.Fatalf("Could not read: %s", err)
+	}
+	sameKey(t, private, parsed)
+
+	// PKCS#1 version 1 and PKCS#8
+	der, err := private.MarshalPKCS1()
+	if err != nil {
+		t.Fatalf("Could not marshal: %s", err)
+	}
+
+	parsed, err = ParsePKCS1PrivateKey(der)
+	if err != nil {
+		t.Fatalf("Could not parse: %s", err)
+	}
+	sameKey(t, private, parsed)
+
+	pem := bytes.Buffer{}
+	if err := private.SavePEM(&pem); err != nil {
+		t.Fatalf("Could not write PEM: %s", err)
+	}
+
+	parsed, err = ParsePrivateKey(pem.Bytes())
+	if err != nil {
+		t.Fatalf("Could not parse PEM: %s", err)
+	}
+	sameKey(t, private, parsed)
+
+	// crypto/x509 reads the same key, including the CRT values of the additional prime
+	std, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		t.Fatalf("crypto/x509 could not parse: %s", err)
+	}
+
+	if err := std.Validate(); err != nil {
+		t.Errorf("crypto/rsa rejected the key: %s", err)
+	}
+
+	if !bytes.Equal(x509.MarshalPKCS1PrivateKey(std), der) {
+		t.Errorf("PKCS#1 encoding does not match crypto/x509")
+	}
+
+	// A corrupted coefficient is caught
+	std.Precomputed.CRTValues[0].Coeff.Add(std.Precomputed.CRTValues[0].Coeff, big.NewInt(1))
+	if _, err := ParsePKCS1PrivateKey(x509.MarshalPKCS1PrivateKey(std)); err == nil {
+		t.Errorf("Parsed a key with a bad coefficient")
+	}
+}
+
+// Multi-prime keys made by crypto/rsa work with our code
+func TestStdlibMultiPrimeKey(t *testing.T) {
+	std, err := rsa.GenerateMultiPrimeKey(rand.Reader, 3, 1024)
+	if err != nil {
+		t.Fatalf("crypto/rsa could not generate a key: %s", err)
+	}
+	private := fromStdlib(t, std)
+
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &std.PublicKey, []byte("Hello World!"), nil)
+	if err != nil {
+		t.Fatalf("crypto/rsa could not encrypt: %s", err)
+	}
+
+	plaintext, err := private.DecryptOAEP(sha256.New(), ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
+
+	if string(plaintext) != "Hello World!" {
+		t.Errorf("Decrypted message does not match original")
+	}
+}
+
+// Each extra prime makes the exponentiations smaller
+func BenchmarkDecryptMultiPrime(b *testing.B) {
+	for _, bits := range []uint{3072, 4096} {
+		for _, count := range []int{2, 3, 4} {
+			b.Run(fmt.Sprintf("%d bits %d primes", bits, count), func(b *testing.B) {
+				private := testMultiPrimeKey(b, bits, count)
+				c, _ := rand.Int(rand.Reader, private.Public.n)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := private.decrypt_block(c); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
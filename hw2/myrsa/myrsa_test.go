@@ -2,24 +2,42 @@ package myrsa
 
 import (
 	"bytes"
-	"fmt"
 	"math/big"
 	"testing"
+
+	"github.com/Alextopher/crypto/numtheory"
 )
 
+// Generates a key or fails the test
+func testKey(t testing.TB, keySize uint) *PrivateKey {
+	t.Helper()
+
+	private, err := Keygen(keySize)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	return private
+}
+
 // Test saving and reading a public key
 func TestSavePublicKey(t *testing.T) {
 	// Keygen
-	private := Keygen(1024)
+	private := testKey(t, 1024)
 
 	// Create a buffer to save the public key
 	buf := bytes.Buffer{}
 
 	// Save the public key
-	private.Public.Save(&buf)
+	if err := private.Public.Save(&buf); err != nil {
+		t.Fatalf("Could not save public key: %s", err)
+	}
 
 	// Create a new public key from the buffer
-	public := ReadPublicKey(&buf)
+	public, err := ReadPublicKey(&buf)
+	if err != nil {
+		t.Fatalf("Could not read public key: %s", err)
+	}
 
 	// Check the public key
 	if public.n.Cmp(private.Public.n) != 0 {
@@ -34,16 +52,21 @@ func TestSavePublicKey(t *testing.T) {
 // Test saving and reading a private key
 func TestSavePrivateKey(t *testing.T) {
 	// Keygen
-	private := Keygen(1024)
+	private := testKey(t, 1024)
 
 	// Create a buffer to save the private key
 	buf := bytes.Buffer{}
 
 	// Save the private key
-	private.Save(&buf)
+	if err := private.Save(&buf); err != nil {
+		t.Fatalf("Could not save private key: %s", err)
+	}
 
 	// Create a new private key from the buffer
-	key := ReadPrivateKey(&buf)
+	key, err := ReadPrivateKey(&buf)
+	if err != nil {
+		t.Fatalf("Could not read private key: %s", err)
+	}
 
 	// Check the private key
 	if key.Public.n.Cmp(private.Public.n) != 0 {
@@ -73,7 +96,7 @@ func TestKeygen(t *testing.T) {
 
 	for _, keysize := range keysizes {
 		// Keygen
-		private := Keygen(uint(keysize))
+		private := testKey(t, uint(keysize))
 
 		// Check the prime sizes
 		if private.p.BitLen() != keysize {
@@ -94,7 +117,7 @@ func TestKeygen(t *testing.T) {
 		phi := new(big.Int).Mul(pm1, qm1)
 
 		// Check that e is coprime to phi
-		if gcd(private.Public.e, phi).Cmp(big.NewInt(1)) != 0 {
+		if numtheory.GCD(private.Public.e, phi).Cmp(big.NewInt(1)) != 0 {
 			t.Error("Public exponent not coprime to phi")
 		}
 
@@ -107,7 +130,7 @@ func TestKeygen(t *testing.T) {
 
 func TestEncrypDecrypt(t *testing.T) {
 	// Keygen
-	private := Keygen(128)
+	private := testKey(t, 128)
 
 	// Create a buffer for the message
 	msgbuf := bytes.Buffer{}
@@ -119,13 +142,17 @@ func TestEncrypDecrypt(t *testing.T) {
 	encbuf := bytes.Buffer{}
 
 	// Encrypt the message
-	private.Public.Encrypt(&msgbuf, &encbuf)
+	if err := private.Public.Encrypt(&msgbuf, &encbuf); err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
 
 	// Create a buffer for the decrypted message
 	decbuf := bytes.Buffer{}
 
 	// Decrypt the message
-	private.Decrypt(&encbuf, &decbuf)
+	if err := private.Decrypt(&encbuf, &decbuf); err != nil {
+		t.Fatalf("Could not decrypt: %s", err)
+	}
 
 	// Check the decrypted message
 	if decbuf.String() != "Hello World!" {
@@ -0,0 +1,112 @@
+package myrsa
+
+import (
+	"crypto/subtle"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// RSAES-OAEP from RFC 8017 section 7.1
+// Unlike Encrypt and EncryptByByte every message is randomly padded before it is encrypted,
+// so the same message never encrypts to the same ciphertext twice.
+
+// ErrDecryption
+// Error returned when a ciphertext could not be decrypted. It intentionally doesn't say why.
+type ErrDecryption struct{}
+
+func (e ErrDecryption) Error() string {
+	return "decryption error"
+}
+
+// EncryptOAEP encrypts msg with RSAES-OAEP. The label isn't encrypted but must be given again to decrypt.
+// msg can be at most k - 2*hash.Size() - 2 bytes long where k is the size of the modulus in bytes.
+func (public *PublicKey) EncryptOAEP(hash hash.Hash, random io.Reader, msg []byte, label []byte) ([]byte, error) {
+	k := public.size()
+	hLen := hash.Size()
+
+	if len(msg) > k-2*hLen-2 {
+		return nil, ErrMessageTooLong{}
+	}
+
+	hash.Reset()
+	hash.Write(label)
+	lHash := hash.Sum(nil)
+
+	// EM = 0x00 || maskedSeed || maskedDB
+	em := make([]byte, k)
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+
+	// DB = lHash || PS || 0x01 || M
+	copy(db, lHash)
+	db[len(db)-len(msg)-1] = 0x01
+	copy(db[len(db)-len(msg):], msg)
+
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, err
+	}
+
+	mgf1XOR(db, hash, seed)
+	mgf1XOR(seed, hash, db)
+
+	c, err := public.encryptBlock(em)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FillBytes(make([]byte, k)), nil
+}
+
+// DecryptOAEP decrypts a ciphertext made by EncryptOAEP with the same hash and label
+func (private *PrivateKey) DecryptOAEP(hash hash.Hash, ciphertext []byte, label []byte) ([]byte, error) {
+	k := private.Public.size()
+	hLen := hash.Size()
+
+	if len(ciphertext) != k || k < 2*hLen+2 {
+		return nil, ErrDecryption{}
+	}
+
+	c := new(big.Int).SetBytes(ciphertext)
+	if c.Cmp(private.Public.n) >= 0 {
+		return nil, ErrDecryption{}
+	}
+
+	m, err := private.decrypt_block(c)
+	if err != nil {
+		return nil, err
+	}
+	em := m.FillBytes(make([]byte, k))
+
+	hash.Reset()
+	hash.Write(label)
+	lHash := hash.Sum(nil)
+
+	seed := em[1 : 1+hLen]
+	db := em[1+hLen:]
+
+	mgf1XOR(seed, hash, db)
+	mgf1XOR(db, hash, seed)
+
+	// Check every part of the padding before deciding, so the time taken doesn't say what was wrong
+	good := subtle.ConstantTimeByteEq(em[0], 0)
+	good &= subtle.ConstantTimeCompare(db[:hLen], lHash)
+
+	// Find the 0x01 separating PS from M
+	lookingForIndex := 1
+	index := 0
+	invalid := 0
+	for i := hLen; i < len(db); i++ {
+		isZero := subtle.ConstantTimeByteEq(db[i], 0)
+		isOne := subtle.ConstantTimeByteEq(db[i], 1)
+		index = subtle.ConstantTimeSelect(lookingForIndex&isOne, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(isOne, 0, lookingForIndex)
+		invalid = subtle.ConstantTimeSelect(lookingForIndex&^isZero, 1, invalid)
+	}
+
+	if good&^invalid&^lookingForIndex != 1 {
+		return nil, ErrDecryption{}
+	}
+
+	return db[index+1:], nil
+}
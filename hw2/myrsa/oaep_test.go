@@ -0,0 +1,164 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+	"testing"
+)
+
+// Converts a myrsa key into the standard library's representation.
+// Go refuses keys with a modulus under 1024 bits, so tests use Keygen(512) or larger.
+func toStdlib(t testing.TB, private *PrivateKey) *rsa.PrivateKey {
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{
+			N: new(big.Int).Set(private.Public.n),
+			E: int(private.Public.e.Int64()),
+		},
+		D:      new(big.Int).Set(private.d),
+		Primes: []*big.Int{new(big.Int).Set(private.p), new(big.Int).Set(private.q)},
+	}
+
+	if err := key.Validate(); err != nil {
+		t.Fatalf("crypto/rsa rejected the key: %s", err)
+	}
+	key.Precompute()
+
+	return key
+}
+
+var oaepVectors = []struct {
+	msg   []byte
+	label []byte
+}{
+	{[]byte(""), nil},
+	{[]byte("Hello World!"), nil},
+	{[]byte("Hello World!"), []byte("label")},
+	{[]byte{0x00, 0x00, 0x01}, []byte("leading zeros")},
+	{bytes.Repeat([]byte{0xff}, 62), nil},
+}
+
+func TestOAEP(t *testing.T) {
+	private := testKey(t, 512)
+
+	for _, v := range oaepVectors {
+		ciphertext, err := private.Public.EncryptOAEP(sha256.New(), rand.Reader, v.msg, v.label)
+		if err != nil {
+			t.Fatalf("Could not encrypt %x: %s", v.msg, err)
+		}
+
+		if len(ciphertext) != private.Public.size() {
+			t.Errorf("Ciphertext is %d bytes, expected %d", len(ciphertext), private.Public.size())
+		}
+
+		plaintext, err := private.DecryptOAEP(sha256.New(), ciphertext, v.label)
+		if err != nil {
+			t.Fatalf("Could not decrypt %x: %s", v.msg, err)
+		}
+
+		if !bytes.Equal(plaintext, v.msg) {
+			t.Errorf("Decrypted %x, expected %x", plaintext, v.msg)
+		}
+
+		if _, err := private.DecryptOAEP(sha256.New(), ciphertext, []byte("wrong label")); err == nil {
+			t.Errorf("Decrypted with the wrong label")
+		}
+	}
+}
+
+// The same message never encrypts to the same ciphertext
+func TestOAEPRandomized(t *testing.T) {
+	private := testKey(t, 512)
+
+	c1, _ := private.Public.EncryptOAEP(sha256.New(), rand.Reader, []byte("A"), nil)
+	c2, _ := private.Public.EncryptOAEP(sha256.New(), rand.Reader, []byte("A"), nil)
+
+	if bytes.Equal(c1, c2) {
+		t.Errorf("OAEP encryption is deterministic")
+	}
+}
+
+func TestOAEPMessageTooLong(t *testing.T) {
+	private := testKey(t, 512)
+
+	// 128 byte modulus - 2 * 32 byte hash - 2
+	_, err := private.Public.EncryptOAEP(sha256.New(), rand.Reader, make([]byte, 63), nil)
+	if _, ok := err.(ErrMessageTooLong); !ok {
+		t.Errorf("Expected ErrMessageTooLong, got %v", err)
+	}
+}
+
+func TestOAEPRejectsTamperedCiphertext(t *testing.T) {
+	private := testKey(t, 512)
+
+	ciphertext, err := private.Public.EncryptOAEP(sha256.New(), rand.Reader, []byte("Hello World!"), nil)
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	for i := 0; i < len(ciphertext); i += 7 {
+		tampered := append([]byte{}, ciphertext...)
+		tampered[i] ^= 0x01
+
+		if _, err := private.DecryptOAEP(sha256.New(), tampered, nil); err == nil {
+			t.Errorf("Decrypted a ciphertext with byte %d flipped", i)
+		}
+	}
+
+	if _, err := private.DecryptOAEP(sha256.New(), ciphertext[1:], nil); err == nil {
+		t.Errorf("Decrypted a short ciphertext")
+	}
+}
+
+// Runs the same vectors through crypto/rsa in both directions
+func TestOAEPInterop(t *testing.T) {
+	private := testKey(t, 512)
+	std := toStdlib(t, private)
+
+	hashes := map[string]func() hash.Hash{
+		"SHA-256": sha256.New,
+		"SHA-384": sha512.New384,
+	}
+
+	for name, h := range hashes {
+		for _, v := range oaepVectors {
+			if len(v.msg) > private.Public.size()-2*h().Size()-2 {
+				continue
+			}
+
+			// myrsa -> crypto/rsa
+			ciphertext, err := private.Public.EncryptOAEP(h(), rand.Reader, v.msg, v.label)
+			if err != nil {
+				t.Fatalf("%s: could not encrypt: %s", name, err)
+			}
+
+			plaintext, err := rsa.DecryptOAEP(h(), nil, std, ciphertext, v.label)
+			if err != nil {
+				t.Fatalf("%s: crypto/rsa could not decrypt: %s", name, err)
+			}
+
+			if !bytes.Equal(plaintext, v.msg) {
+				t.Errorf("%s: crypto/rsa decrypted %x, expected %x", name, plaintext, v.msg)
+			}
+
+			// crypto/rsa -> myrsa
+			ciphertext, err = rsa.EncryptOAEP(h(), rand.Reader, &std.PublicKey, v.msg, v.label)
+			if err != nil {
+				t.Fatalf("%s: crypto/rsa could not encrypt: %s", name, err)
+			}
+
+			plaintext, err = private.DecryptOAEP(h(), ciphertext, v.label)
+			if err != nil {
+				t.Fatalf("%s: could not decrypt: %s", name, err)
+			}
+
+			if !bytes.Equal(plaintext, v.msg) {
+				t.Errorf("%s: decrypted %x, expected %x", name, plaintext, v.msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/Alextopher/crypto/fingerprint"
+	"github.com/Alextopher/crypto/keyfile"
+)
+
+// DER and PEM encodings for keys so they can be exchanged with other tools
+// Private keys are PKCS#1 (RFC 8017 appendix A.1.2) or PKCS#8 (RFC 5208)
+// Public keys are PKCS#1 or SubjectPublicKeyInfo (RFC 5280)
+
+// The PKCS#1 RSAPrivateKey structure
+// Version 1 keys have more than two primes
+type pkcs1PrivateKey struct {
+	Version          int
+	N                *big.Int
+	E                *big.Int
+	D                *big.Int
+	P                *big.Int
+	Q                *big.Int
+	Dp               *big.Int
+	Dq               *big.Int
+	Qinv             *big.Int
+	AdditionalPrimes []pkcs1AdditionalPrime `asn1:"optional,omitempty"`
+}
+
+// The OtherPrimeInfo structure for each prime after the second
+type pkcs1AdditionalPrime struct {
+	Prime       *big.Int
+	Exponent    *big.Int
+	Coefficient *big.Int
+}
+
+// The PKCS#1 RSAPublicKey structure
+type pkcs1PublicKey struct {
+	N *big.Int
+	E *big.Int
+}
+
+// The PKCS#8 PrivateKeyInfo structure
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// The SubjectPublicKeyInfo structure
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// rsaEncryption from PKCS#1, the parameters must be NULL
+var rsaAlgorithm = pkix.AlgorithmIdentifier{
+	Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1},
+	Parameters: asn1.NullRawValue,
+}
+
+// Builds the PKCS#1 structure for a key, computing the CRT parameters
+// The exponent for every prime r is d mod r-1, the coefficient of q is q^-1 mod p,
+// and the coefficient of every later prime r_i is (r_1 * ... * r_i-1)^-1 mod r_i
+func newPKCS1PrivateKey(n, e, d *big.Int, primes []*big.Int) (pkcs1PrivateKey, error) {
+	one := big.NewInt(1)
+	exponents := make([]*big.Int, len(primes))
+	coefficients := make([]*big.Int, len(primes))
+
+	product := new(big.Int).Set(primes[0])
+	for i, r := range primes {
+		exponents[i] = new(big.Int).Mod(d, new(big.Int).Sub(r, one))
+
+		if i > 0 {
+			coefficients[i] = new(big.Int).ModInverse(product, r)
+			if coefficients[i] == nil {
+				return pkcs1PrivateKey{}, fmt.Errorf("%w: primes are not coprime", ErrInconsistentKey{})
+			}
+			product.Mul(product, r)
+		}
+	}
+
+	key := pkcs1PrivateKey{
+		Version: 0,
+		N:       n,
+		E:       e,
+		D:       d,
+		P:       primes[0],
+		Q:       primes[1],
+		Dp:      exponents[0],
+		Dq:      exponents[1],
+		// q^-1 mod p
+		Qinv: new(big.Int).ModInverse(primes[1], primes[0]),
+	}
+
+	for i := 2; i < len(primes); i++ {
+		key.Version = 1
+		key.AdditionalPrimes = append(key.AdditionalPrimes, pkcs1AdditionalPrime{primes[i], exponents[i], coefficients[i]})
+	}
+
+	return key, nil
+}
+
+// MarshalPKCS1 encodes the private key as a DER PKCS#1 RSAPrivateKey, including the CRT parameters
+func (private *PrivateKey) MarshalPKCS1() ([]byte, error) {
+	key, err := newPKCS1PrivateKey(private.Public.n, private.Public.e, private.d, private.primes())
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(key)
+}
+
+// ParsePKCS1PrivateKey decodes a DER PKCS#1 RSAPrivateKey and validates it
+func ParsePKCS1PrivateKey(der []byte) (*PrivateKey, error) {
+	return parsePKCS1PrivateKey(der, true)
+}
+
+func parsePKCS1PrivateKey(der []byte, validate bool) (*PrivateKey, error) {
+	var key pkcs1PrivateKey
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: PKCS#1 private key: %v", ErrMalformedKey{}, err)
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: trailing data after PKCS#1 private key", ErrMalformedKey{})
+	}
+
+	if key.Version < 0 || key.Version > 1 || (key.Version == 1) != (len(key.AdditionalPrimes) > 0) {
+		return nil, fmt.Errorf("%w: unsupported PKCS#1 private key version %d", ErrMalformedKey{}, key.Version)
+	}
+
+	primes := []*big.Int{key.P, key.Q}
+	for _, additional := range key.AdditionalPrimes {
+		primes = append(primes, additional.Prime)
+	}
+
+	if err := checkPrivateKey(key.N, key.E, key.D, primes); err != nil {
+		return nil, err
+	}
+
+	private := newPrivateKey(key.N, key.E, key.D, primes)
+	if !validate {
+		return private, nil
+	}
+
+	if err := private.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The CRT parameters aren't kept, but they must agree with the rest of the key
+	expected, err := newPKCS1PrivateKey(key.N, key.E, key.D, primes)
+	if err != nil {
+		return nil, err
+	}
+
+	same := key.Dp.Cmp(expected.Dp) == 0 && key.Dq.Cmp(expected.Dq) == 0 && key.Qinv.Cmp(expected.Qinv) == 0
+	for i, additional := range key.AdditionalPrimes {
+		same = same && additional.Exponent.Cmp(expected.AdditionalPrimes[i].Exponent) == 0 &&
+			additional.Coefficient.Cmp(expected.AdditionalPrimes[i].Coefficient) == 0
+	}
+
+	if !same {
+		return nil, fmt.Errorf("%w: CRT parameters do not match", ErrInconsistentKey{})
+	}
+
+	return private, nil
+}
+
+// MarshalPKCS8 encodes the private key as a DER PKCS#8 PrivateKeyInfo
+func (private *PrivateKey) MarshalPKCS8() ([]byte, error) {
+	inner, err := private.MarshalPKCS1()
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8PrivateKey{
+		Version:    0,
+		Algorithm:  rsaAlgorithm,
+		PrivateKey: inner,
+	})
+}
+
+// ParsePKCS8PrivateKey decodes a DER PKCS#8 PrivateKeyInfo holding an RSA key and validates it
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	return parsePKCS8PrivateKey(der, true)
+}
+
+func parsePKCS8PrivateKey(der []byte, validate bool) (*PrivateKey, error) {
+	var key pkcs8PrivateKey
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: PKCS#8 private key: %v", ErrMalformedKey{}, err)
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: trailing data after PKCS#8 private key", ErrMalformedKey{})
+	}
+
+	if !key.Algorithm.Algorithm.Equal(rsaAlgorithm.Algorithm) {
+		return nil, fmt.Errorf("%w: PKCS#8 private key is not an RSA key (%v)", ErrMalformedKey{}, key.Algorithm.Algorithm)
+	}
+
+	return parsePKCS1PrivateKey(key.PrivateKey, validate)
+}
+
+// MarshalPKCS1 encodes the public key as a DER PKCS#1 RSAPublicKey
+func (public *PublicKey) MarshalPKCS1() ([]byte, error) {
+	return asn1.Marshal(pkcs1PublicKey{public.n, public.e})
+}
+
+// ParsePKCS1PublicKey decodes a DER PKCS#1 RSAPublicKey
+func ParsePKCS1PublicKey(der []byte) (*PublicKey, error) {
+	var key pkcs1PublicKey
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: PKCS#1 public key: %v", ErrMalformedKey{}, err)
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: trailing data after PKCS#1 public key", ErrMalformedKey{})
+	}
+
+	if err := checkPublicKey(key.N, key.E); err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{key.N, key.E}, nil
+}
+
+// MarshalPKIX encodes the public key as a DER SubjectPublicKeyInfo
+func (public *PublicKey) MarshalPKIX() ([]byte, error) {
+	inner, err := public.MarshalPKCS1()
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: rsaAlgorithm,
+		PublicKey: asn1.BitString{Bytes: inner, BitLength: 8 * len(inner)},
+	})
+}
+
+// Fingerprint is the SHA-256 hash of the DER SubjectPublicKeyInfo, the same as
+// openssl pkey -pubin -outform DER | sha256sum, so it doesn't change with the file format
+func (public *PublicKey) Fingerprint() (fingerprint.Fingerprint, error) {
+	der, err := public.MarshalPKIX()
+	if err != nil {
+		return fingerprint.Fingerprint{}, err
+	}
+
+	return fingerprint.Of(der), nil
+}
+
+// ParsePKIXPublicKey decodes a DER SubjectPublicKeyInfo holding an RSA key
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var key subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: public key: %v", ErrMalformedKey{}, err)
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: trailing data after public key", ErrMalformedKey{})
+	}
+
+	if !key.Algorithm.Algorithm.Equal(rsaAlgorithm.Algorithm) {
+		return nil, fmt.Errorf("%w: public key is not an RSA key (%v)", ErrMalformedKey{}, key.Algorithm.Algorithm)
+	}
+
+	return ParsePKCS1PublicKey(key.PublicKey.RightAlign())
+}
+
+// SavePEM writes the private key as a PEM encoded PKCS#8 "PRIVATE KEY"
+func (private *PrivateKey) SavePEM(w io.Writer) error {
+	der, err := private.MarshalPKCS8()
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// SavePEM writes the public key as a PEM encoded SubjectPublicKeyInfo "PUBLIC KEY"
+func (public *PublicKey) SavePEM(w io.Writer) error {
+	der, err := public.MarshalPKIX()
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// SaveEncryptedPEM writes the private key as a PEM "ENCRYPTED PRIVATE KEY", PKCS#8 encrypted with
+// a key derived from the passphrase by scrypt, openssl reads it with the same passphrase
+func (private *PrivateKey) SaveEncryptedPEM(w io.Writer, passphrase []byte) error {
+	der, err := private.MarshalPKCS8()
+	if err != nil {
+		return err
+	}
+
+	return keyfile.EncryptPEM(w, der, passphrase, keyfile.DefaultParams)
+}
+
+// ErrEncryptedKey
+// Error returned when ParsePrivateKey is given an encrypted key, it needs ParseEncryptedPrivateKey and the passphrase
+type ErrEncryptedKey struct{}
+
+func (e ErrEncryptedKey) Error() string {
+	return "private key is encrypted with a passphrase"
+}
+
+// IsEncryptedPrivateKey reports whether a key file holds a PEM "ENCRYPTED PRIVATE KEY"
+func IsEncryptedPrivateKey(data []byte) bool {
+	return keyfile.IsEncrypted(data)
+}
+
+// ParseEncryptedPrivateKey decrypts a PEM "ENCRYPTED PRIVATE KEY" written by SaveEncryptedPEM
+// or openssl pkcs8 -topk8 -scrypt -v2 aes-128-cbc and validates it
+// A wrong passphrase returns keyfile.ErrIncorrectPassphrase
+func ParseEncryptedPrivateKey(data, passphrase []byte) (*PrivateKey, error) {
+	return parseEncryptedPrivateKey(data, passphrase, true)
+}
+
+// ParseEncryptedPrivateKeyUnchecked decrypts a private key like ParseEncryptedPrivateKey but skips Validate
+func ParseEncryptedPrivateKeyUnchecked(data, passphrase []byte) (*PrivateKey, error) {
+	return parseEncryptedPrivateKey(data, passphrase, false)
+}
+
+func parseEncryptedPrivateKey(data, passphrase []byte, validate bool) (*PrivateKey, error) {
+	der, err := keyfile.DecryptPEM(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePKCS8PrivateKey(der, validate)
+}
+
+// Files starting with this are PEM, anything else is the legacy decimal format
+var pemPrefix = []byte("-----BEGIN")
+
+// ParsePrivateKey reads a private key in any supported format and validates it:
+// PEM "RSA PRIVATE KEY" (PKCS#1), PEM "PRIVATE KEY" (PKCS#8) or the legacy decimal format written by Save
+func ParsePrivateKey(data []byte) (*PrivateKey, error) {
+	return parsePrivateKey(data, true)
+}
+
+// ParsePrivateKeyUnchecked reads a private key like ParsePrivateKey but skips Validate,
+// so a broken key can still be loaded and inspected with Check
+func ParsePrivateKeyUnchecked(data []byte) (*PrivateKey, error) {
+	return parsePrivateKey(data, false)
+}
+
+func parsePrivateKey(data []byte, validate bool) (*PrivateKey, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), pemPrefix) {
+		return readPrivateKey(bytes.NewReader(data), validate)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: invalid PEM", ErrMalformedKey{})
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return parsePKCS1PrivateKey(block.Bytes, validate)
+	case "PRIVATE KEY":
+		return parsePKCS8PrivateKey(block.Bytes, validate)
+	case keyfile.PEMType:
+		return nil, ErrEncryptedKey{}
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrMalformedKey{}, block.Type)
+	}
+}
+
+// ParsePublicKey reads a public key in any supported format:
+// PEM "RSA PUBLIC KEY" (PKCS#1), PEM "PUBLIC KEY" (SubjectPublicKeyInfo) or the legacy decimal format written by Save
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), pemPrefix) {
+		return ReadPublicKey(bytes.NewReader(data))
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: invalid PEM", ErrMalformedKey{})
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		return ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrMalformedKey{}, block.Type)
+	}
+}
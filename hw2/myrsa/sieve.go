@@ -0,0 +1,103 @@
+package myrsa
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// Trial division uses the odd primes below sieveLimit, candidates must be larger than this
+const sieveLimit = 1 << 14
+
+// The number of odd candidates in each sieve window
+const sieveWindow = 1 << 12
+
+// The odd primes below sieveLimit
+var smallPrimes = numtheory.SmallPrimes(sieveLimit)[1:]
+
+// A source of prime candidates
+// Candidates from the same window are close together, so a key only takes one prime from each window.
+type candidateSource interface {
+	next() (value *big.Int, window uint, err error)
+}
+
+// Searches upwards from a random odd starting point, crossing out every number
+// in the window with a small factor so Miller-Rabin only sees the survivors
+type sieveCandidates struct {
+	random io.Reader
+	bits   uint
+
+	// The window holds base, base + 2, ..., base + 2 * (sieveWindow - 1)
+	base      *big.Int
+	composite []bool
+	i         int
+
+	// The number of windows started
+	windows uint
+}
+
+func newSieveCandidates(random io.Reader, bits uint) *sieveCandidates {
+	return &sieveCandidates{random: random, bits: bits, composite: make([]bool, sieveWindow), i: sieveWindow}
+}
+
+// Starts a new window from a fresh random candidate
+func (s *sieveCandidates) fill() error {
+	base, err := primeCandidate(s.random, s.bits)
+	if err != nil {
+		return err
+	}
+
+	for i := range s.composite {
+		s.composite[i] = false
+	}
+
+	m := new(big.Int)
+	for _, p := range smallPrimes {
+		// base + 2k = 0 mod p when k = -base / 2 mod p, and 1/2 = (p + 1) / 2 mod p
+		r := m.Mod(base, m.SetUint64(p)).Uint64()
+		k := (p - r) % p * ((p + 1) / 2) % p
+
+		for ; k < sieveWindow; k += p {
+			s.composite[k] = true
+		}
+	}
+
+	s.base = base
+	s.i = 0
+	s.windows++
+	return nil
+}
+
+// The next number in the window without a small factor
+func (s *sieveCandidates) next() (*big.Int, uint, error) {
+	for {
+		if s.i == len(s.composite) {
+			if err := s.fill(); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		i := s.i
+		s.i++
+
+		if s.composite[i] {
+			continue
+		}
+
+		c := new(big.Int).Add(s.base, big.NewInt(2*int64(i)))
+
+		// The window ran past the largest number with this many bits
+		if c.BitLen() != int(s.bits) {
+			s.i = len(s.composite)
+			continue
+		}
+
+		return c, s.windows, nil
+	}
+}
+
+// Where randomPrimes gets its candidates, replaced in benchmarks
+var newCandidates = func(random io.Reader, bits uint) candidateSource {
+	return newSieveCandidates(random, bits)
+}
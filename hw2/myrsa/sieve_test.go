@@ -0,0 +1,127 @@
+package myrsa
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// Every candidate tested separately, the search before the sieve
+type randomCandidates struct {
+	random io.Reader
+	bits   uint
+	tries  *uint
+}
+
+func (r randomCandidates) next() (*big.Int, uint, error) {
+	*r.tries++
+	c, err := primeCandidate(r.random, r.bits)
+	return c, *r.tries, err
+}
+
+// Replaces newCandidates for the rest of the test
+func injectCandidates(tb testing.TB, fn func(random io.Reader, bits uint) candidateSource) {
+	original := newCandidates
+	newCandidates = fn
+	tb.Cleanup(func() { newCandidates = original })
+}
+
+func TestSmallPrimes(t *testing.T) {
+	if len(smallPrimes) != 1899 {
+		t.Errorf("Expected 1899 odd primes below %d, got %d", sieveLimit, len(smallPrimes))
+	}
+
+	for _, p := range smallPrimes {
+		if !new(big.Int).SetUint64(p).ProbablyPrime(0) {
+			t.Fatalf("%d is not prime", p)
+		}
+	}
+}
+
+// The sieve only removes numbers with a small factor, and never runs past bits
+func TestSieveCandidates(t *testing.T) {
+	for _, bits := range []uint{16, 17, 64, 512} {
+		source := newSieveCandidates(seededReader(int64(bits)), bits)
+		previous, window := new(big.Int), uint(0)
+
+		for i := 0; i < 2*sieveWindow; i++ {
+			c, w, err := source.next()
+			if err != nil {
+				t.Fatalf("Could not read candidate: %s", err)
+			}
+
+			if c.BitLen() != int(bits) || c.Bit(0) == 0 {
+				t.Fatalf("Candidate %s is not an odd %d bit number", c, bits)
+			}
+
+			for _, p := range smallPrimes {
+				if new(big.Int).Mod(c, new(big.Int).SetUint64(p)).Sign() == 0 {
+					t.Fatalf("Candidate %s is divisible by %d", c, p)
+				}
+			}
+
+			// Inside a window every number without a small factor is returned in order
+			if w == window {
+				for m := new(big.Int).Add(previous, big.NewInt(2)); m.Cmp(c) < 0; m.Add(m, big.NewInt(2)) {
+					if m.ProbablyPrime(0) {
+						t.Fatalf("The sieve skipped the prime %s", m)
+					}
+				}
+			}
+			previous, window = c, w
+		}
+	}
+}
+
+func BenchmarkRandomPrimes(b *testing.B) {
+	sources := []struct {
+		name   string
+		source func(random io.Reader, bits uint) candidateSource
+	}{
+		{"random", func(random io.Reader, bits uint) candidateSource { return randomCandidates{random, bits, new(uint)} }},
+		{"sieve", newCandidates},
+	}
+
+	for _, bits := range []uint{1024, 2048} {
+		for _, source := range sources {
+			for _, test := range []PrimalityTest{MillerRabin, BailliePSW} {
+				if source.name == "random" && test == BailliePSW {
+					continue
+				}
+
+				b.Run(fmt.Sprintf("%d bits %s %s", bits, source.name, test), func(b *testing.B) {
+					injectCandidates(b, source.source)
+					opts := KeygenOptions{Random: seededReader(1), Workers: 1, Test: test}
+					e := big.NewInt(65537)
+
+					tries := uint(0)
+					for i := 0; i < b.N; i++ {
+						_, total, err := randomPrimes(context.Background(), &opts, bits, 1, e, tries)
+						if err != nil {
+							b.Fatal(err)
+						}
+						tries = total
+					}
+
+					b.ReportMetric(float64(tries)/float64(b.N), "tries/op")
+				})
+			}
+		}
+	}
+}
+
+// Two primes from one window would be close enough for Fermat factorization
+func TestKeygenSieveWindows(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		private, err := KeygenWithOptions(context.Background(), KeygenOptions{Bits: 512, Primes: 3, Random: seededReader(seed)})
+		if err != nil {
+			t.Fatalf("Could not generate key: %s", err)
+		}
+
+		if report := private.Check(); len(report.Errors) != 0 || len(report.Warnings) != 0 {
+			t.Errorf("Key has problems: %v", report)
+		}
+	}
+}
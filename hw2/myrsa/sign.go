@@ -0,0 +1,290 @@
+package myrsa
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// RSASSA-PKCS1-v1_5 and RSASSA-PSS signatures from RFC 8017 sections 8.1 and 8.2
+// Both sign a digest of the message, which the caller computes with the matching hash.
+
+// ErrVerification
+// Error returned when a signature does not match the message
+type ErrVerification struct{}
+
+func (e ErrVerification) Error() string {
+	return "verification error"
+}
+
+// ErrUnsupportedHash
+// Error returned when a signature is requested with a hash other than SHA-256, SHA-384 or SHA-512
+type ErrUnsupportedHash struct{}
+
+func (e ErrUnsupportedHash) Error() string {
+	return "unsupported hash function"
+}
+
+// The DER encoded DigestInfo prefix for each supported hash
+var hashPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// Makes sure the hash is supported and digest is the right length for it
+func checkDigest(hash crypto.Hash, digest []byte) error {
+	if _, ok := hashPrefixes[hash]; !ok || !hash.Available() {
+		return ErrUnsupportedHash{}
+	}
+
+	if len(digest) != hash.Size() {
+		return errors.New("digest length does not match the hash")
+	}
+
+	return nil
+}
+
+const (
+	// PSSSaltLengthAuto signs with the largest salt that fits and detects the salt length when verifying
+	PSSSaltLengthAuto = 0
+	// PSSSaltLengthEqualsHash uses a salt as long as the hash
+	PSSSaltLengthEqualsHash = -1
+)
+
+// PSSOptions selects RSASSA-PSS in Sign and Verify
+type PSSOptions struct {
+	// The length of the salt, or one of the PSSSaltLength constants
+	SaltLength int
+
+	// The hash used for the digest and inside the encoding
+	Hash crypto.Hash
+}
+
+// HashFunc returns opts.Hash so PSSOptions can be used as crypto.SignerOpts
+func (opts *PSSOptions) HashFunc() crypto.Hash {
+	return opts.Hash
+}
+
+// Sign signs digest with RSASSA-PSS if opts is a *PSSOptions and RSASSA-PKCS1-v1_5 otherwise
+func (private *PrivateKey) Sign(random io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if pssOpts, ok := opts.(*PSSOptions); ok {
+		return private.SignPSS(random, pssOpts.Hash, digest, pssOpts)
+	}
+
+	return private.SignPKCS1v15(opts.HashFunc(), digest)
+}
+
+// Verify checks a signature made by Sign with the same opts
+func (public *PublicKey) Verify(digest, sig []byte, opts crypto.SignerOpts) error {
+	if pssOpts, ok := opts.(*PSSOptions); ok {
+		return public.VerifyPSS(pssOpts.Hash, digest, sig, pssOpts)
+	}
+
+	return public.VerifyPKCS1v15(opts.HashFunc(), digest, sig)
+}
+
+// Applies the private key to an encoded message and returns the signature as k bytes
+func (private *PrivateKey) signBlock(em []byte) ([]byte, error) {
+	m := new(big.Int).SetBytes(em)
+
+	s, err := private.decrypt_block(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FillBytes(make([]byte, private.Public.size())), nil
+}
+
+// Applies the public key to a signature and returns the encoded message as emLen bytes
+func (public *PublicKey) verifyBlock(sig []byte, emLen int) ([]byte, error) {
+	if len(sig) != public.size() {
+		return nil, ErrVerification{}
+	}
+
+	s := new(big.Int).SetBytes(sig)
+	if s.Cmp(public.n) >= 0 {
+		return nil, ErrVerification{}
+	}
+
+	m := new(big.Int).Exp(s, public.e, public.n)
+	if (m.BitLen()+7)/8 > emLen {
+		return nil, ErrVerification{}
+	}
+
+	return m.FillBytes(make([]byte, emLen)), nil
+}
+
+// EM = 0x00 || 0x01 || PS || 0x00 || DigestInfo
+func pkcs1v15Encode(hash crypto.Hash, digest []byte, k int) ([]byte, error) {
+	if err := checkDigest(hash, digest); err != nil {
+		return nil, err
+	}
+
+	prefix := hashPrefixes[hash]
+	tLen := len(prefix) + len(digest)
+	if k < tLen+11 {
+		return nil, ErrMessageTooLong{}
+	}
+
+	em := make([]byte, k)
+	em[1] = 0x01
+	for i := 2; i < k-tLen-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[k-tLen:], prefix)
+	copy(em[k-len(digest):], digest)
+
+	return em, nil
+}
+
+// SignPKCS1v15 signs the digest of a message with RSASSA-PKCS1-v1_5
+func (private *PrivateKey) SignPKCS1v15(hash crypto.Hash, digest []byte) ([]byte, error) {
+	em, err := pkcs1v15Encode(hash, digest, private.Public.size())
+	if err != nil {
+		return nil, err
+	}
+
+	return private.signBlock(em)
+}
+
+// VerifyPKCS1v15 checks an RSASSA-PKCS1-v1_5 signature over the digest of a message
+func (public *PublicKey) VerifyPKCS1v15(hash crypto.Hash, digest, sig []byte) error {
+	k := public.size()
+
+	expected, err := pkcs1v15Encode(hash, digest, k)
+	if err != nil {
+		return err
+	}
+
+	em, err := public.verifyBlock(sig, k)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(em, expected) != 1 {
+		return ErrVerification{}
+	}
+
+	return nil
+}
+
+// The salt length to sign with for the given options
+func pssSaltLength(opts *PSSOptions, hash crypto.Hash) int {
+	if opts == nil {
+		return PSSSaltLengthAuto
+	}
+
+	if opts.SaltLength == PSSSaltLengthEqualsHash {
+		return hash.Size()
+	}
+
+	return opts.SaltLength
+}
+
+// M' = 0x00 * 8 || mHash || salt, H = Hash(M')
+func pssHash(hash crypto.Hash, digest, salt []byte) []byte {
+	h := hash.New()
+	h.Write(make([]byte, 8))
+	h.Write(digest)
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+// SignPSS signs the digest of a message with RSASSA-PSS
+func (private *PrivateKey) SignPSS(random io.Reader, hash crypto.Hash, digest []byte, opts *PSSOptions) ([]byte, error) {
+	if err := checkDigest(hash, digest); err != nil {
+		return nil, err
+	}
+
+	emBits := private.Public.n.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	hLen := hash.Size()
+
+	sLen := pssSaltLength(opts, hash)
+	if sLen == PSSSaltLengthAuto {
+		sLen = emLen - hLen - 2
+	}
+
+	if sLen < 0 || emLen < hLen+sLen+2 {
+		return nil, ErrMessageTooLong{}
+	}
+
+	salt := make([]byte, sLen)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, err
+	}
+
+	// EM = maskedDB || H || 0xbc
+	em := make([]byte, emLen)
+	db := em[:emLen-hLen-1]
+	h := em[emLen-hLen-1 : emLen-1]
+	em[emLen-1] = 0xbc
+
+	copy(h, pssHash(hash, digest, salt))
+
+	// DB = PS || 0x01 || salt
+	db[len(db)-sLen-1] = 0x01
+	copy(db[len(db)-sLen:], salt)
+
+	mgf1XOR(db, hash.New(), h)
+
+	// Clear the bits above emBits
+	db[0] &= 0xff >> (8*emLen - emBits)
+
+	return private.signBlock(em)
+}
+
+// VerifyPSS checks an RSASSA-PSS signature over the digest of a message
+func (public *PublicKey) VerifyPSS(hash crypto.Hash, digest, sig []byte, opts *PSSOptions) error {
+	if err := checkDigest(hash, digest); err != nil {
+		return err
+	}
+
+	emBits := public.n.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	hLen := hash.Size()
+
+	em, err := public.verifyBlock(sig, emLen)
+	if err != nil {
+		return err
+	}
+
+	if emLen < hLen+2 || em[emLen-1] != 0xbc {
+		return ErrVerification{}
+	}
+
+	db := em[:emLen-hLen-1]
+	h := em[emLen-hLen-1 : emLen-1]
+
+	// The bits above emBits must be zero
+	if db[0]&^(0xff>>(8*emLen-emBits)) != 0 {
+		return ErrVerification{}
+	}
+
+	mgf1XOR(db, hash.New(), h)
+	db[0] &= 0xff >> (8*emLen - emBits)
+
+	// DB = 0x00 ... 0x00 || 0x01 || salt
+	separator := bytes.IndexByte(db, 0x01)
+	if separator < 0 || !bytes.Equal(db[:separator], make([]byte, separator)) {
+		return ErrVerification{}
+	}
+	salt := db[separator+1:]
+
+	sLen := pssSaltLength(opts, hash)
+	if sLen != PSSSaltLengthAuto && len(salt) != sLen {
+		return ErrVerification{}
+	}
+
+	if subtle.ConstantTimeCompare(h, pssHash(hash, digest, salt)) != 1 {
+		return ErrVerification{}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package myrsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+)
+
+var signatureHashes = []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512}
+
+func digest(hash crypto.Hash, msg string) []byte {
+	h := hash.New()
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+func TestSignPKCS1v15(t *testing.T) {
+	private := testKey(t, 640)
+	std := toStdlib(t, private)
+
+	for _, hash := range signatureHashes {
+		t.Run(hash.String(), func(t *testing.T) {
+			d := digest(hash, "Hello World!")
+
+			sig, err := private.SignPKCS1v15(hash, d)
+			if err != nil {
+				t.Fatalf("Could not sign: %s", err)
+			}
+
+			if err := private.Public.VerifyPKCS1v15(hash, d, sig); err != nil {
+				t.Errorf("Valid signature did not verify: %s", err)
+			}
+
+			if err := rsa.VerifyPKCS1v15(&std.PublicKey, hash, d, sig); err != nil {
+				t.Errorf("crypto/rsa rejected the signature: %s", err)
+			}
+
+			// PKCS#1 v1.5 signatures are deterministic, so they must match crypto/rsa exactly
+			stdSig, err := rsa.SignPKCS1v15(nil, std, hash, d)
+			if err != nil {
+				t.Fatalf("crypto/rsa could not sign: %s", err)
+			}
+
+			if string(stdSig) != string(sig) {
+				t.Errorf("Signature does not match crypto/rsa")
+			}
+
+			if err := private.Public.VerifyPKCS1v15(hash, digest(hash, "Hello World?"), sig); err == nil {
+				t.Errorf("Signature verified for a different message")
+			}
+		})
+	}
+}
+
+func TestSignPSS(t *testing.T) {
+	private := testKey(t, 640)
+	std := toStdlib(t, private)
+
+	for _, hash := range signatureHashes {
+		for _, saltLength := range []int{PSSSaltLengthAuto, PSSSaltLengthEqualsHash, 20} {
+			t.Run(fmt.Sprintf("%s salt %d", hash, saltLength), func(t *testing.T) {
+				d := digest(hash, "Hello World!")
+				opts := &PSSOptions{SaltLength: saltLength, Hash: hash}
+				stdOpts := &rsa.PSSOptions{SaltLength: saltLength, Hash: hash}
+
+				sig, err := private.SignPSS(rand.Reader, hash, d, opts)
+				if err != nil {
+					t.Fatalf("Could not sign: %s", err)
+				}
+
+				if err := private.Public.VerifyPSS(hash, d, sig, opts); err != nil {
+					t.Errorf("Valid signature did not verify: %s", err)
+				}
+
+				if err := rsa.VerifyPSS(&std.PublicKey, hash, d, sig, stdOpts); err != nil {
+					t.Errorf("crypto/rsa rejected the signature: %s", err)
+				}
+
+				stdSig, err := rsa.SignPSS(rand.Reader, std, hash, d, stdOpts)
+				if err != nil {
+					t.Fatalf("crypto/rsa could not sign: %s", err)
+				}
+
+				if err := private.Public.VerifyPSS(hash, d, stdSig, opts); err != nil {
+					t.Errorf("crypto/rsa signature did not verify: %s", err)
+				}
+
+				if err := private.Public.VerifyPSS(hash, digest(hash, "Hello World?"), sig, opts); err == nil {
+					t.Errorf("Signature verified for a different message")
+				}
+			})
+		}
+	}
+}
+
+// Sign and Verify pick the scheme from the options
+func TestSignerOpts(t *testing.T) {
+	private := testKey(t, 512)
+	d := digest(crypto.SHA256, "Hello World!")
+
+	for _, opts := range []crypto.SignerOpts{crypto.SHA256, &PSSOptions{Hash: crypto.SHA256}} {
+		sig, err := private.Sign(rand.Reader, d, opts)
+		if err != nil {
+			t.Fatalf("Could not sign: %s", err)
+		}
+
+		if err := private.Public.Verify(d, sig, opts); err != nil {
+			t.Errorf("Valid signature did not verify: %s", err)
+		}
+	}
+
+	// A PKCS#1 v1.5 signature isn't a PSS signature
+	sig, _ := private.Sign(rand.Reader, d, crypto.SHA256)
+	if err := private.Public.Verify(d, sig, &PSSOptions{Hash: crypto.SHA256}); err == nil {
+		t.Errorf("PKCS#1 v1.5 signature verified as PSS")
+	}
+}
+
+func TestSignRejectsBadInput(t *testing.T) {
+	private := testKey(t, 512)
+	d := digest(crypto.SHA256, "Hello World!")
+
+	if _, err := private.SignPKCS1v15(crypto.SHA1, make([]byte, 20)); err == nil {
+		t.Errorf("Signed with an unsupported hash")
+	}
+
+	if _, err := private.SignPKCS1v15(crypto.SHA256, d[:31]); err == nil {
+		t.Errorf("Signed a digest of the wrong length")
+	}
+
+	sig, _ := private.SignPKCS1v15(crypto.SHA256, d)
+	for i := 0; i < len(sig); i += 9 {
+		tampered := append([]byte{}, sig...)
+		tampered[i] ^= 0x80
+		if err := private.Public.VerifyPKCS1v15(crypto.SHA256, d, tampered); err == nil {
+			t.Errorf("Signature with byte %d flipped verified", i)
+		}
+	}
+
+	if err := private.Public.VerifyPKCS1v15(crypto.SHA256, d, sig[1:]); err == nil {
+		t.Errorf("Short signature verified")
+	}
+}
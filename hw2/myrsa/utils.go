@@ -1,44 +1,64 @@
 package myrsa
 
 import (
-	"fmt"
+	"hash"
 	"math/big"
 )
 
 const WITNESS_COUNT = 20
 
-// The pulverizer
-func pulverizer(a, b *big.Int) (d, x, y *big.Int) {
-	x1, y1, x2, y2 := big.NewInt(1), big.NewInt(0), big.NewInt(0), big.NewInt(1)
-	for b.Cmp(big.NewInt(0)) != 0 {
-		q, r := new(big.Int).DivMod(a, b, new(big.Int))
-		fmt.Println("a:", a, "b:", b, "q:", q, "r:", r, "x1:", x1, "y1:", y1, "x2:", x2, "y2:", y2)
-		a, b = b, r
-		x1, x2 = x2, new(big.Int).Sub(x1, new(big.Int).Mul(q, x2))
-		y1, y2 = y2, new(big.Int).Sub(y1, new(big.Int).Mul(q, y2))
+// Prepares a^b mod n as partial results to be used by the chinese remainder theorem
+// Every prime r is handled by its own goroutine
+// x_r = (a mod r) ^ (b mod r-1) mod r
+func flt(a, b *big.Int, primes []*big.Int) []*big.Int {
+	results := make([]chan *big.Int, len(primes))
+
+	for i, r := range primes {
+		results[i] = make(chan *big.Int)
+
+		go func(r *big.Int, result chan<- *big.Int) {
+			// (a mod r) ^ (b mod r-1) mod r
+			rm1 := new(big.Int).Sub(r, big.NewInt(1))
+			result <- new(big.Int).Exp(new(big.Int).Mod(a, r), new(big.Int).Mod(b, rm1), r)
+		}(r, results[i])
 	}
 
-	return a, x1, y1
+	partials := make([]*big.Int, len(primes))
+	for i := range partials {
+		partials[i] = <-results[i]
+	}
+
+	return partials
 }
 
-// Prepares a^b mod n as partial results to be used by the chinese remainder theorem
-// x = (a mod p) ^ (b mod p-1) mod p
-// y = (a mod q) ^ (b mod q-1) mod q
-func flt(a, b, p, q *big.Int) (*big.Int, *big.Int) {
-	x := make(chan *big.Int)
-	y := make(chan *big.Int)
-
-	go func() {
-		// (a mod p) ^ (b mod p-1) mod p
-		pm1 := new(big.Int).Sub(p, big.NewInt(1))
-		x <- new(big.Int).Exp(new(big.Int).Mod(a, p), new(big.Int).Mod(b, pm1), p)
-	}()
-
-	go func() {
-		// (a mod q) ^ (b mod q-1) mod q
-		qm1 := new(big.Int).Sub(q, big.NewInt(1))
-		y <- new(big.Int).Exp(new(big.Int).Mod(a, q), new(big.Int).Mod(b, qm1), q)
-	}()
-
-	return <-x, <-y
+// Mask generation function MGF1 from RFC 8017 appendix B.2.1
+// xors out with hash(seed || counter) for counter = 0, 1, 2, ...
+func mgf1XOR(out []byte, hash hash.Hash, seed []byte) {
+	counter := make([]byte, 4)
+	done := 0
+
+	for done < len(out) {
+		hash.Reset()
+		hash.Write(seed)
+		hash.Write(counter)
+		digest := hash.Sum(nil)
+
+		for i := 0; i < len(digest) && done < len(out); i++ {
+			out[done] ^= digest[i]
+			done++
+		}
+
+		// increment the big endian counter
+		for i := 3; i >= 0; i-- {
+			counter[i]++
+			if counter[i] != 0 {
+				break
+			}
+		}
+	}
+}
+
+// The number of bytes needed to hold the modulus
+func (public *PublicKey) size() int {
+	return (public.n.BitLen() + 7) / 8
 }
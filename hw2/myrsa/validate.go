@@ -0,0 +1,134 @@
+package myrsa
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// KeyReport lists everything Check found wrong with a private key
+type KeyReport struct {
+	// Invariants the key breaks, it can't be used to decrypt or sign
+	Errors []string
+
+	// Weaknesses that don't stop the key from working but make it easier to break
+	Warnings []string
+}
+
+// Carmichael's function λ(n) = lcm(p-1, q-1, ...) for the primes of n
+func carmichael(primes ...*big.Int) *big.Int {
+	one := big.NewInt(1)
+	lambda := big.NewInt(1)
+
+	for _, r := range primes {
+		rm1 := new(big.Int).Sub(r, one)
+		g := numtheory.GCD(lambda, rm1)
+		lambda.Mul(lambda, rm1)
+		lambda.Div(lambda, g)
+	}
+
+	return lambda
+}
+
+// |a - b| <= 2^(bits/2 - 100) where bits is the size of a * b
+func tooClose(a, b *big.Int) bool {
+	diff := new(big.Int).Sub(a, b)
+	diff.Abs(diff)
+
+	bits := new(big.Int).Mul(a, b).BitLen()
+	bound := bits/2 - 100
+	return bound <= 0 || diff.BitLen() <= bound
+}
+
+// The name of prime i in reports: p, q, r3, r4, ...
+func primeName(i int) string {
+	switch i {
+	case 0:
+		return "p"
+	case 1:
+		return "q"
+	default:
+		return fmt.Sprintf("r%d", i+1)
+	}
+}
+
+// Check tests every invariant of the key and reports all of the ones that fail
+//
+//	n = p * q (* r3 * ... for multi-prime keys)
+//	p > q
+//	every prime is prime and they are all different
+//	e * d = 1 mod λ(n)
+//
+// It also warns when two primes are close enough for Fermat's factorization method,
+// using the bound |p - q| <= 2^(nlen/2 - 100) from FIPS 186-5 appendix A.1.3
+func (private *PrivateKey) Check() KeyReport {
+	report := KeyReport{}
+	fail := func(format string, args ...interface{}) {
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+	}
+
+	n, e, d, primes := private.Public.n, private.Public.e, private.d, private.primes()
+	if err := checkPrivateKey(n, e, d, primes); err != nil {
+		fail("%s", err)
+		return report
+	}
+
+	names := make([]string, len(primes))
+	product := big.NewInt(1)
+	for i, r := range primes {
+		names[i] = primeName(i)
+		product.Mul(product, r)
+	}
+
+	if product.Cmp(n) != 0 {
+		fail("n != %s", strings.Join(names, " * "))
+	}
+
+	if private.p.Cmp(private.q) < 0 {
+		fail("p < q")
+	}
+
+	for i, r := range primes {
+		if !numtheory.MillerRabin(r, WITNESS_COUNT) {
+			fail("%s is not prime", names[i])
+		}
+	}
+
+	for i := range primes {
+		for j := i + 1; j < len(primes); j++ {
+			if primes[i].Cmp(primes[j]) == 0 {
+				fail("%s == %s", names[i], names[j])
+			}
+		}
+	}
+
+	lambda := carmichael(primes...)
+	if numtheory.GCD(e, lambda).Cmp(big.NewInt(1)) != 0 {
+		fail("e is not coprime to λ(n)")
+	} else if ed := new(big.Int).Mul(e, d); ed.Mod(ed, lambda).Cmp(big.NewInt(1)) != 0 {
+		fail("e * d != 1 mod λ(n)")
+	}
+
+	for i := range primes {
+		for j := i + 1; j < len(primes); j++ {
+			if tooClose(primes[i], primes[j]) {
+				diff := new(big.Int).Sub(primes[i], primes[j])
+				report.Warnings = append(report.Warnings, fmt.Sprintf("|%s - %s| is %d bits, small enough for Fermat factorization", names[i], names[j], diff.Abs(diff).BitLen()))
+			}
+		}
+	}
+
+	return report
+}
+
+// Validate returns an ErrInconsistentKey listing every invariant the key breaks, warnings are ignored
+func (private *PrivateKey) Validate() error {
+	report := private.Check()
+	if len(report.Errors) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrInconsistentKey{}, strings.Join(report.Errors, ", "))
+}
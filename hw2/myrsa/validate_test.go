@@ -0,0 +1,137 @@
+package myrsa
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// Copies a key so it can be corrupted
+func cloneKey(private *PrivateKey) *PrivateKey {
+	primes := []*big.Int{}
+	for _, r := range private.primes() {
+		primes = append(primes, new(big.Int).Set(r))
+	}
+
+	n := new(big.Int).Set(private.Public.n)
+	e := new(big.Int).Set(private.Public.e)
+	return newPrivateKey(n, e, new(big.Int).Set(private.d), primes)
+}
+
+// Checks the report has an error containing each of the expected strings
+func expectErrors(t *testing.T, report KeyReport, expected ...string) {
+	t.Helper()
+
+	for _, want := range expected {
+		found := false
+		for _, got := range report.Errors {
+			if strings.Contains(got, want) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("Expected %q in %q", want, report.Errors)
+		}
+	}
+}
+
+func TestValidateGoodKeys(t *testing.T) {
+	for _, keySize := range []uint{128, 256, 512} {
+		private := testKey(t, keySize)
+
+		if err := private.Validate(); err != nil {
+			t.Errorf("Valid %d bit key failed validation: %s", keySize, err)
+		}
+
+		if report := private.Check(); len(report.Warnings) != 0 {
+			t.Errorf("Valid %d bit key has warnings: %q", keySize, report.Warnings)
+		}
+	}
+}
+
+func TestValidateCorruptedKeys(t *testing.T) {
+	private := testKey(t, 256)
+	one := big.NewInt(1)
+
+	tests := []struct {
+		name     string
+		corrupt  func(k *PrivateKey)
+		expected []string
+	}{
+		{"Wrong modulus", func(k *PrivateKey) { k.Public.n.Add(k.Public.n, big.NewInt(2)) }, []string{"n != p * q"}},
+		{"Swapped primes", func(k *PrivateKey) { k.p, k.q = k.q, k.p }, []string{"p < q"}},
+		{"Equal primes", func(k *PrivateKey) { k.q.Set(k.p); k.Public.n.Mul(k.p, k.q) }, []string{"p == q"}},
+		{"Composite p", func(k *PrivateKey) { k.p.Mul(k.p, big.NewInt(3)) }, []string{"p is not prime", "n != p * q"}},
+		{"Composite q", func(k *PrivateKey) { k.q.Mul(k.q, k.q); k.Public.n.Mul(k.p, k.q) }, []string{"q is not prime"}},
+		{"Wrong d", func(k *PrivateKey) { k.d.Add(k.d, one) }, []string{"e * d != 1 mod λ(n)"}},
+		{"Even e", func(k *PrivateKey) { k.Public.e.SetInt64(65536) }, []string{"e is not coprime to λ(n)"}},
+		{"Zero d", func(k *PrivateKey) { k.d.SetInt64(0) }, []string{"malformed"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := cloneKey(private)
+			test.corrupt(key)
+
+			expectErrors(t, key.Check(), test.expected...)
+
+			err := key.Validate()
+			if !errors.As(err, &ErrInconsistentKey{}) && !errors.As(err, &ErrMalformedKey{}) {
+				t.Errorf("Expected a key error, got %v", err)
+			}
+		})
+	}
+}
+
+// Primes that are too close together can be found by Fermat's method
+func TestValidateFermatWarning(t *testing.T) {
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+
+	// Find two primes of 256 bits that differ in the low 64 bits only
+	p := testKey(t, 256).p
+	q := new(big.Int).Add(p, new(big.Int).Lsh(one, 64))
+	for !q.ProbablyPrime(20) || numtheory.GCD(new(big.Int).Sub(q, one), e).Cmp(one) != 0 {
+		q.Add(q, big.NewInt(2))
+	}
+	p, q = q, p
+
+	d := new(big.Int).ModInverse(e, carmichael(p, q))
+	private := newPrivateKey(new(big.Int).Mul(p, q), e, d, []*big.Int{p, q})
+
+	report := private.Check()
+	if len(report.Errors) != 0 {
+		t.Fatalf("Key has errors: %q", report.Errors)
+	}
+
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "Fermat") {
+		t.Errorf("Expected a Fermat warning, got %q", report.Warnings)
+	}
+
+	if err := private.Validate(); err != nil {
+		t.Errorf("Warnings should not fail validation: %s", err)
+	}
+}
+
+// Corrupted keys can't be loaded, unless they are loaded to be checked
+func TestParseCorruptedKey(t *testing.T) {
+	private := cloneKey(testKey(t, 256))
+	private.d.Add(private.d, big.NewInt(2))
+
+	data := lines(private.Public.n, private.Public.e, private.d, private.p, private.q)
+
+	if _, err := ParsePrivateKey([]byte(data)); !errors.As(err, &ErrInconsistentKey{}) {
+		t.Errorf("Expected ErrInconsistentKey, got %v", err)
+	}
+
+	key, err := ParsePrivateKeyUnchecked([]byte(data))
+	if err != nil {
+		t.Fatalf("Could not load the key to check it: %s", err)
+	}
+
+	expectErrors(t, key.Check(), "e * d != 1 mod λ(n)")
+}
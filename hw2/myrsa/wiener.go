@@ -0,0 +1,59 @@
+package myrsa
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Wiener recovers d when d < n^(1/4) / 3 from the continued fraction of e / n.
+// ed = 1 + kφ(n) and φ(n) ≈ n, so k / d is one of its convergents.
+func Wiener(public *PublicKey) (*PrivateKey, error) {
+	n, e := public.n, public.e
+	one := big.NewInt(1)
+
+	// Convergents h / k of e / n, h is the guess for k and k the guess for d
+	h0, h1 := big.NewInt(0), big.NewInt(1)
+	k0, k1 := big.NewInt(1), big.NewInt(0)
+	num, den := new(big.Int).Set(e), new(big.Int).Set(n)
+
+	for den.Sign() != 0 {
+		a, r := new(big.Int).QuoRem(num, den, new(big.Int))
+		num, den = den, r
+
+		h0, h1 = h1, new(big.Int).Add(new(big.Int).Mul(a, h1), h0)
+		k0, k1 = k1, new(big.Int).Add(new(big.Int).Mul(a, k1), k0)
+
+		k, d := h1, k1
+		if k.Sign() == 0 {
+			continue
+		}
+
+		// φ = (ed - 1) / k must be a whole number
+		phi, rem := new(big.Int).Mul(e, d), new(big.Int)
+		phi.Sub(phi, one).QuoRem(phi, k, rem)
+		if rem.Sign() != 0 {
+			continue
+		}
+
+		// p and q are the roots of x^2 - (n - φ + 1)x + n
+		s := new(big.Int).Sub(n, phi)
+		s.Add(s, one)
+		disc := new(big.Int).Mul(s, s)
+		disc.Sub(disc, new(big.Int).Lsh(n, 2))
+		if disc.Sign() < 0 {
+			continue
+		}
+
+		root := new(big.Int).Sqrt(disc)
+		if new(big.Int).Mul(root, root).Cmp(disc) != 0 {
+			continue
+		}
+
+		p := s.Add(s, root).Rsh(s, 1)
+		if private, err := recoverKey(public, p); err == nil {
+			return private, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: d is not small enough", ErrAttackFailed{})
+}
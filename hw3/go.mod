@@ -1,3 +1,7 @@
 module github.com/Alextopher/crypto/hw3
 
 go 1.17
+
+require github.com/Alextopher/crypto/numtheory v0.0.0
+
+replace github.com/Alextopher/crypto/numtheory => ../numtheory
@@ -9,6 +9,8 @@ import (
 	"os"
 	"regexp"
 	"strings"
+
+	"github.com/Alextopher/crypto/numtheory"
 )
 
 func main() {
@@ -37,7 +39,11 @@ func main() {
 		fullmask := new(big.Int).Exp(halfmask, a, p)
 
 		// modular inverse of fullmask
-		modinv := new(big.Int).ModInverse(fullmask, p)
+		modinv, err := numtheory.ModInverse(fullmask, p)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
 		// Decrypt the cipher
 		plain := new(big.Int).Mul(cipher, modinv)
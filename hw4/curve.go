@@ -3,22 +3,9 @@ package main
 import (
 	"fmt"
 	"math/big"
-)
 
-// // The pulverizer
-// // gcd(a, b) = d = xa + yb mod a
-// func pulverizer(a, b *big.Int) (d, x, y *big.Int) {
-// 	x1, y1, x2, y2 := big.NewInt(1), big.NewInt(0), big.NewInt(0), big.NewInt(1)
-// 	for b.Cmp(big.NewInt(0)) != 0 {
-// 		q, r := new(big.Int).DivMod(a, b, new(big.Int))
-// 		// fmt.Println("a:", a, "b:", b, "q:", q, "r:", r, "x1:", x1, "y1:", y1, "x2:", x2, "y2:", y2)
-// 		a, b = b, r
-// 		x1, x2 = x2, new(big.Int).Sub(x1, new(big.Int).Mul(q, x2))
-// 		y1, y2 = y2, new(big.Int).Sub(y1, new(big.Int).Mul(q, y2))
-// 	}
-
-// 	return a, x1, y1
-// }
+	"github.com/Alextopher/crypto/numtheory"
+)
 
 // y^2 = x^3 + ax + b (mod p)
 type EllipticCurve struct {
@@ -76,7 +63,10 @@ func (ec *EllipticCurve) Add(p1, p2 *Point) *Point {
 	dy := new(big.Int).Sub(p2.Y, p1.Y)
 	dx := new(big.Int).Sub(p2.X, p1.X)
 	// find the modular inverse of dx
-	m := new(big.Int).ModInverse(dx, ec.P)
+	m, err := numtheory.ModInverse(dx, ec.P)
+	if err != nil {
+		return &Point{big.NewInt(0), big.NewInt(0)}
+	}
 	m = m.Mul(m, dy)
 	m = m.Mod(m, ec.P)
 
@@ -114,8 +104,11 @@ func (ec *EllipticCurve) Double(p *Point) *Point {
 
 	twoY := new(big.Int).Add(p.Y, p.Y)
 	twoY = twoY.Mod(twoY, ec.P)
-	// find the modular inverse of twoY
-	m := new(big.Int).ModInverse(twoY, ec.P)
+	// find the modular inverse of twoY, the tangent is vertical when y = 0
+	m, err := numtheory.ModInverse(twoY, ec.P)
+	if err != nil {
+		return &Point{big.NewInt(0), big.NewInt(0)}
+	}
 	m = m.Mul(m, threeX2a)
 	m = m.Mod(m, ec.P)
 
@@ -1,3 +1,7 @@
 module github.com/Alextopher/crypto/h4
 
 go 1.18
+
+require github.com/Alextopher/crypto/numtheory v0.0.0
+
+replace github.com/Alextopher/crypto/numtheory => ../numtheory
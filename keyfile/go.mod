@@ -0,0 +1,7 @@
+module github.com/Alextopher/crypto/keyfile
+
+go 1.17
+
+require github.com/Alextopher/crypto/aes v0.0.0
+
+replace github.com/Alextopher/crypto/aes => ../aes
@@ -0,0 +1,324 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Alextopher/crypto/aes"
+)
+
+// Private keys encrypted under a passphrase, stored as a PKCS#8 EncryptedPrivateKeyInfo (RFC 5208 section 6)
+// The key is derived with scrypt (RFC 7914 section 7) and encrypts the DER key with AES-128-CBC, this is PBES2 (RFC 8018 section 6.2)
+// so openssl pkey reads the RSA keys
+
+// PEMType is the type of the PEM block holding an encrypted key
+const PEMType = "ENCRYPTED PRIVATE KEY"
+
+// ErrIncorrectPassphrase
+// Error returned when a key can't be decrypted with the passphrase
+type ErrIncorrectPassphrase struct{}
+
+func (e ErrIncorrectPassphrase) Error() string {
+	return "incorrect passphrase"
+}
+
+// ErrMalformed
+// Error returned when an encrypted key can't be parsed or uses an algorithm we don't support
+type ErrMalformed struct{}
+
+func (e ErrMalformed) Error() string {
+	return "malformed encrypted key"
+}
+
+// Params are the scrypt costs, memory use is 128 * R * N bytes
+type Params struct {
+	N, R, P int
+}
+
+// DefaultParams are the costs openssl uses, 16MiB and around 50ms
+var DefaultParams = Params{N: 1 << 14, R: 8, P: 1}
+
+// The most memory a key file may ask for when it is decrypted, 256MiB
+const maxMemory = 256 << 20
+
+const (
+	saltSize     = 16
+	aesKeySize   = 16
+	aesBlockSize = 16
+)
+
+var (
+	oidPBES2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidScrypt    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+// Wraps DER encoded parameters in an AlgorithmIdentifier
+func algorithm(oid asn1.ObjectIdentifier, params interface{}) (pkix.AlgorithmIdentifier, error) {
+	der, err := asn1.Marshal(params)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+
+	return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.RawValue{FullBytes: der}}, nil
+}
+
+// Encrypt encrypts a DER private key under the passphrase and returns the DER EncryptedPrivateKeyInfo
+func Encrypt(der, passphrase []byte, params Params) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	iv := make([]byte, aesBlockSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	key, err := Scrypt(passphrase, salt, params.N, params.R, params.P, aesKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := encryptCBC(key, iv, der)
+	if err != nil {
+		return nil, err
+	}
+
+	kdf, err := algorithm(oidScrypt, scryptParams{salt, params.N, params.R, params.P, aesKeySize})
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, err := algorithm(oidAES128CBC, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	pbes2, err := algorithm(oidPBES2, pbes2Params{kdf, scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{pbes2, ciphertext})
+}
+
+// Decrypt decrypts a DER EncryptedPrivateKeyInfo written by Encrypt, or by openssl with -scrypt -v2 aes-128-cbc
+func Decrypt(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if err := unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("%w: unsupported encryption %v, expected PBES2", ErrMalformed{}, info.Algorithm.Algorithm)
+	}
+
+	var pbes2 pbes2Params
+	if err := unmarshal(info.Algorithm.Parameters.FullBytes, &pbes2); err != nil {
+		return nil, err
+	}
+
+	if !pbes2.KeyDerivationFunc.Algorithm.Equal(oidScrypt) {
+		return nil, fmt.Errorf("%w: unsupported key derivation %v, expected scrypt", ErrMalformed{}, pbes2.KeyDerivationFunc.Algorithm)
+	}
+
+	if !pbes2.EncryptionScheme.Algorithm.Equal(oidAES128CBC) {
+		return nil, fmt.Errorf("%w: unsupported cipher %v, expected AES-128-CBC", ErrMalformed{}, pbes2.EncryptionScheme.Algorithm)
+	}
+
+	var params scryptParams
+	if err := unmarshal(pbes2.KeyDerivationFunc.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+
+	if params.KeyLength != 0 && params.KeyLength != aesKeySize {
+		return nil, fmt.Errorf("%w: scrypt key length %d, expected %d", ErrMalformed{}, params.KeyLength, aesKeySize)
+	}
+
+	// A file could ask for more memory than we have
+	if params.BlockSize <= 0 || params.CostParameter <= 0 || params.ParallelizationParameter <= 0 ||
+		params.CostParameter > maxMemory/128/params.BlockSize || params.ParallelizationParameter > 16 {
+		return nil, fmt.Errorf("%w: scrypt parameters are out of range", ErrMalformed{})
+	}
+
+	var iv []byte
+	if err := unmarshal(pbes2.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+
+	if len(iv) != aesBlockSize {
+		return nil, fmt.Errorf("%w: IV is %d bytes, expected %d", ErrMalformed{}, len(iv), aesBlockSize)
+	}
+
+	key, err := Scrypt(passphrase, params.Salt, params.CostParameter, params.BlockSize, params.ParallelizationParameter, aesKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed{}, err)
+	}
+
+	plaintext, err := decryptCBC(key, iv, info.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	// There's no MAC, a wrong passphrase is caught by the padding or by the key not being a DER SEQUENCE
+	var seq asn1.RawValue
+	if rest, err := asn1.Unmarshal(plaintext, &seq); err != nil || len(rest) > 0 || seq.Tag != asn1.TagSequence {
+		return nil, ErrIncorrectPassphrase{}
+	}
+
+	return plaintext, nil
+}
+
+// Parses DER with no trailing data
+func unmarshal(der []byte, v interface{}) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed{}, err)
+	}
+
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: trailing data", ErrMalformed{})
+	}
+
+	return nil
+}
+
+// EncryptPEM encrypts a DER private key and writes it as a PEM "ENCRYPTED PRIVATE KEY"
+func EncryptPEM(w io.Writer, der, passphrase []byte, params Params) error {
+	encrypted, err := Encrypt(der, passphrase, params)
+	if err != nil {
+		return err
+	}
+
+	return pem.Encode(w, &pem.Block{Type: PEMType, Bytes: encrypted})
+}
+
+// DecryptPEM decrypts a PEM "ENCRYPTED PRIVATE KEY" and returns the DER private key
+func DecryptPEM(data, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMType {
+		return nil, fmt.Errorf("%w: expected a PEM %q block", ErrMalformed{}, PEMType)
+	}
+
+	return Decrypt(block.Bytes, passphrase)
+}
+
+// IsEncrypted reports whether a key file starts with a PEM "ENCRYPTED PRIVATE KEY" block and needs a passphrase
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN "+PEMType+"-----"))
+}
+
+// Encrypts with AES-128-CBC, padding the plaintext with PKCS#7
+func encryptCBC(key, iv, plaintext []byte) ([]byte, error) {
+	cipher, err := aes.NewAES(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aesBlockSize - len(plaintext)%aesBlockSize
+	ciphertext := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	prev := iv
+	for i := 0; i < len(ciphertext); i += aesBlockSize {
+		block := ciphertext[i : i+aesBlockSize]
+		xor(block, prev)
+		cipher.Encrypt(block, block)
+		prev = block
+	}
+
+	return ciphertext, nil
+}
+
+// Decrypts AES-128-CBC and removes the PKCS#7 padding
+func decryptCBC(key, iv, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%aesBlockSize != 0 {
+		return nil, fmt.Errorf("%w: ciphertext is not a multiple of the block size", ErrMalformed{})
+	}
+
+	cipher, err := aes.NewAES(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	prev := iv
+	for i := 0; i < len(ciphertext); i += aesBlockSize {
+		block := plaintext[i : i+aesBlockSize]
+		cipher.Decrypt(block, ciphertext[i:i+aesBlockSize])
+		xor(block, prev)
+		prev = ciphertext[i : i+aesBlockSize]
+	}
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aesBlockSize {
+		return nil, ErrIncorrectPassphrase{}
+	}
+
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, ErrIncorrectPassphrase{}
+		}
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// dst ^= src
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// WriteFile writes a key file atomically: the data goes to a temporary file in the same directory,
+// which is synced and renamed over name, so a crash never leaves a half written or stale key
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
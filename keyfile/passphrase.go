@@ -0,0 +1,84 @@
+package keyfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoTerminal
+// Error returned when a passphrase is needed but there's no terminal to ask on
+type ErrNoTerminal struct{}
+
+func (e ErrNoTerminal) Error() string {
+	return "no terminal to read the passphrase from"
+}
+
+// ReadPassphrase returns the passphrase in the environment variable env when it is set,
+// otherwise it prints the prompt on the terminal and reads a line without echoing it
+func ReadPassphrase(env, prompt string) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(env); ok {
+		return []byte(passphrase), nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w, set %s", ErrNoTerminal{}, env)
+	}
+	defer tty.Close()
+
+	return readPassphrase(tty, prompt)
+}
+
+// NewPassphrase is ReadPassphrase for a passphrase that is being chosen, the terminal asks for it twice to catch typos
+func NewPassphrase(env, prompt string) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(env); ok {
+		return []byte(passphrase), nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w, set %s", ErrNoTerminal{}, env)
+	}
+	defer tty.Close()
+
+	passphrase, err := readPassphrase(tty, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	again, err := readPassphrase(tty, "Enter the same passphrase again: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(passphrase, again) {
+		return nil, errors.New("passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+// Prints the prompt and reads a line with echo turned off
+func readPassphrase(tty *os.File, prompt string) ([]byte, error) {
+	fmt.Fprint(tty, prompt)
+
+	restore, err := disableEcho(tty.Fd())
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := bufio.NewReader(tty).ReadBytes('\n')
+	restore()
+
+	// The newline wasn't echoed
+	fmt.Fprintln(tty)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,68 @@
+package keyfile
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Bad hex in test: %s", err)
+	}
+	return b
+}
+
+// Test vectors from RFC 7914 section 11
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		expected       string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+
+	for _, test := range tests {
+		key := pbkdf2([]byte(test.password), []byte(test.salt), test.iterations, 64)
+		if !bytes.Equal(key, decodeHex(t, test.expected)) {
+			t.Errorf("PBKDF2(%q, %q, %d) = %x", test.password, test.salt, test.iterations, key)
+		}
+	}
+}
+
+// Test vectors from RFC 7914 section 12
+func TestScrypt(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		n, r, p        int
+		expected       string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+		{"pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+	}
+
+	for _, test := range tests {
+		key, err := Scrypt([]byte(test.password), []byte(test.salt), test.n, test.r, test.p, 64)
+		if err != nil {
+			t.Fatalf("Scrypt failed: %s", err)
+		}
+
+		if !bytes.Equal(key, decodeHex(t, test.expected)) {
+			t.Errorf("Scrypt(%q, %q, %d, %d, %d) = %x", test.password, test.salt, test.n, test.r, test.p, key)
+		}
+	}
+}
+
+func TestScryptBadParameters(t *testing.T) {
+	for _, params := range [][3]int{{0, 8, 1}, {1, 8, 1}, {1000, 8, 1}, {16, 0, 1}, {16, 8, 0}, {16, 1 << 20, 1 << 10}} {
+		if _, err := Scrypt([]byte("password"), []byte("salt"), params[0], params[1], params[2], 32); err == nil {
+			t.Errorf("Scrypt accepted n = %d, r = %d, p = %d", params[0], params[1], params[2])
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package keyfile
+
+import "syscall"
+
+const (
+	getTermios = syscall.TCGETS
+	setTermios = syscall.TCSETS
+)
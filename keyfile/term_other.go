@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package keyfile
+
+// Other systems echo the passphrase
+func disableEcho(fd uintptr) (restore func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin
+
+package keyfile
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func ioctl(fd, request uintptr, termios *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Stops the terminal echoing what is typed, restore turns it back on
+func disableEcho(fd uintptr) (restore func(), err error) {
+	var termios syscall.Termios
+	if err := ioctl(fd, getTermios, &termios); err != nil {
+		return nil, err
+	}
+
+	old := termios
+	termios.Lflag &^= syscall.ECHO
+	termios.Lflag |= syscall.ICANON | syscall.ISIG
+	if err := ioctl(fd, setTermios, &termios); err != nil {
+		return nil, err
+	}
+
+	return func() { ioctl(fd, setTermios, &old) }, nil
+}
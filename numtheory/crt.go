@@ -0,0 +1,35 @@
+package numtheory
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// CRT finds the x in [0, n) with x = residues[i] mod moduli[i] for every i,
+// where n is the product of the moduli. The moduli must be pairwise coprime.
+func CRT(residues, moduli []*big.Int) (x, n *big.Int, err error) {
+	if len(residues) != len(moduli) || len(moduli) == 0 {
+		return nil, nil, errors.New("CRT needs one residue for each modulus")
+	}
+
+	x, n = big.NewInt(0), big.NewInt(1)
+	for i, m := range moduli {
+		if m.Sign() <= 0 {
+			return nil, nil, fmt.Errorf("modulus %s is not positive", m)
+		}
+
+		// x' = x + n * ((r - x) / n mod m) keeps x' = x mod n and makes x' = r mod m
+		inverse, err := ModInverse(n, m)
+		if err != nil {
+			return nil, nil, fmt.Errorf("moduli are not pairwise coprime: %w", err)
+		}
+
+		t := new(big.Int).Sub(residues[i], x)
+		t.Mul(t, inverse).Mod(t, m)
+		x.Add(x, t.Mul(t, n))
+		n.Mul(n, m)
+	}
+
+	return x.Mod(x, n), n, nil
+}
@@ -0,0 +1,74 @@
+package numtheory
+
+import (
+	"math/big"
+	"testing"
+)
+
+// Converts a list of numbers
+func ints(values ...int64) []*big.Int {
+	result := make([]*big.Int, len(values))
+	for i, v := range values {
+		result[i] = big.NewInt(v)
+	}
+	return result
+}
+
+func TestCRT(t *testing.T) {
+	tests := []struct {
+		residues, moduli []int64
+		x, n             int64
+		ok               bool
+	}{
+		{[]int64{2, 3, 2}, []int64{3, 5, 7}, 23, 105, true},
+		{[]int64{1}, []int64{7}, 1, 7, true},
+		{[]int64{10}, []int64{7}, 3, 7, true},
+		{[]int64{-1, -1}, []int64{4, 9}, 35, 36, true},
+		{[]int64{0, 3, 4}, []int64{3, 4, 5}, 39, 60, true},
+		{[]int64{1, 2, 3, 4}, []int64{5, 7, 9, 11}, 1731, 3465, true},
+		{[]int64{1, 2}, []int64{4, 6}, 0, 0, false},
+		{[]int64{1, 2}, []int64{5}, 0, 0, false},
+		{[]int64{}, []int64{}, 0, 0, false},
+		{[]int64{1}, []int64{0}, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		x, n, err := CRT(ints(test.residues...), ints(test.moduli...))
+		if !test.ok {
+			if err == nil {
+				t.Errorf("CRT(%v, %v) did not fail", test.residues, test.moduli)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("CRT(%v, %v) failed: %s", test.residues, test.moduli, err)
+		} else if x.Int64() != test.x || n.Int64() != test.n {
+			t.Errorf("CRT(%v, %v) = %s mod %s, expected %d mod %d", test.residues, test.moduli, x, n, test.x, test.n)
+		}
+	}
+}
+
+// Large moduli, like the cube roots in Håstad's broadcast attack
+func TestCRTLarge(t *testing.T) {
+	moduli := []*big.Int{
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1)),
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 89), big.NewInt(1)),
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 61), big.NewInt(1)),
+	}
+
+	secret, _ := new(big.Int).SetString("123456789012345678901234567890123456789", 10)
+	residues := make([]*big.Int, len(moduli))
+	for i, m := range moduli {
+		residues[i] = new(big.Int).Mod(secret, m)
+	}
+
+	x, _, err := CRT(residues, moduli)
+	if err != nil {
+		t.Fatalf("CRT failed: %s", err)
+	}
+
+	if x.Cmp(secret) != 0 {
+		t.Errorf("CRT gave %s, expected %s", x, secret)
+	}
+}
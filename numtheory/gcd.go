@@ -0,0 +1,50 @@
+package numtheory
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ErrNotInvertible
+// Error returned when a number shares a factor with the modulus
+type ErrNotInvertible struct{}
+
+func (e ErrNotInvertible) Error() string {
+	return "number is not invertible"
+}
+
+// GCD of two numbers with Euclid's algorithm
+func GCD(a, b *big.Int) *big.Int {
+	for b.Sign() != 0 {
+		a, b = b, new(big.Int).Mod(a, b)
+	}
+
+	return new(big.Int).Set(a)
+}
+
+// ExtendedGCD is the pulverizer, it finds d = gcd(a, b) and x, y with d = ax + by
+func ExtendedGCD(a, b *big.Int) (d, x, y *big.Int) {
+	x1, y1, x2, y2 := big.NewInt(1), big.NewInt(0), big.NewInt(0), big.NewInt(1)
+	for b.Sign() != 0 {
+		q, r := new(big.Int).DivMod(a, b, new(big.Int))
+		a, b = b, r
+		x1, x2 = x2, new(big.Int).Sub(x1, new(big.Int).Mul(q, x2))
+		y1, y2 = y2, new(big.Int).Sub(y1, new(big.Int).Mul(q, y2))
+	}
+
+	return new(big.Int).Set(a), x1, y1
+}
+
+// ModInverse finds x in [0, n) with a * x = 1 mod n
+func ModInverse(a, n *big.Int) (*big.Int, error) {
+	if n.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: modulus %s is not positive", ErrNotInvertible{}, n)
+	}
+
+	d, x, _ := ExtendedGCD(new(big.Int).Mod(a, n), n)
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return nil, fmt.Errorf("%w: gcd(%s, %s) = %s", ErrNotInvertible{}, a, n, d)
+	}
+
+	return x.Mod(x, n), nil
+}
@@ -0,0 +1,80 @@
+package numtheory
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestGCD(t *testing.T) {
+	tests := []struct {
+		a, b, d int64
+	}{
+		{0, 0, 0},
+		{0, 7, 7},
+		{7, 0, 7},
+		{12, 18, 6},
+		{18, 12, 6},
+		{17, 5, 1},
+		{240, 46, 2},
+		{-12, 18, 6},
+	}
+
+	for _, test := range tests {
+		a, b := big.NewInt(test.a), big.NewInt(test.b)
+
+		if d := GCD(a, b); d.Int64() != test.d {
+			t.Errorf("GCD(%d, %d) = %s, expected %d", test.a, test.b, d, test.d)
+		}
+
+		d, x, y := ExtendedGCD(a, b)
+		if d.Int64() != test.d {
+			t.Errorf("ExtendedGCD(%d, %d) = %s, expected %d", test.a, test.b, d, test.d)
+		}
+
+		// d = ax + by
+		ax := new(big.Int).Mul(a, x)
+		if ax.Add(ax, new(big.Int).Mul(b, y)).Cmp(d) != 0 {
+			t.Errorf("ExtendedGCD(%d, %d) gave %s * %d + %s * %d != %s", test.a, test.b, x, test.a, y, test.b, d)
+		}
+
+		// The inputs are untouched
+		if a.Int64() != test.a || b.Int64() != test.b {
+			t.Errorf("ExtendedGCD(%d, %d) changed its inputs", test.a, test.b)
+		}
+	}
+}
+
+func TestModInverse(t *testing.T) {
+	tests := []struct {
+		a, n, inverse int64
+		ok            bool
+	}{
+		{3, 11, 4, true},
+		{10, 17, 12, true},
+		{-3, 11, 7, true},
+		{14, 11, 4, true},
+		{1, 2, 1, true},
+		{5, 1, 0, true},
+		{6, 9, 0, false},
+		{0, 7, 0, false},
+		{3, 0, 0, false},
+		{3, -11, 0, false},
+	}
+
+	for _, test := range tests {
+		inverse, err := ModInverse(big.NewInt(test.a), big.NewInt(test.n))
+		if !test.ok {
+			if !errors.As(err, &ErrNotInvertible{}) {
+				t.Errorf("ModInverse(%d, %d) expected ErrNotInvertible, got %v", test.a, test.n, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ModInverse(%d, %d) failed: %s", test.a, test.n, err)
+		} else if inverse.Int64() != test.inverse {
+			t.Errorf("ModInverse(%d, %d) = %s, expected %d", test.a, test.n, inverse, test.inverse)
+		}
+	}
+}
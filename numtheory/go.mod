@@ -0,0 +1,3 @@
+module github.com/Alextopher/crypto/numtheory
+
+go 1.17
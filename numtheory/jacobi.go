@@ -0,0 +1,39 @@
+package numtheory
+
+import "math/big"
+
+// Jacobi symbol (a/n) for an odd positive n, it panics otherwise
+// Implemented from the algorithm in
+// https://en.wikipedia.org/wiki/Jacobi_symbol#Calculating_the_Jacobi_symbol
+func Jacobi(a, n *big.Int) int {
+	if n.Sign() <= 0 || n.Bit(0) == 0 {
+		panic("numtheory: Jacobi symbol needs an odd positive n")
+	}
+
+	a = new(big.Int).Mod(a, n)
+	n = new(big.Int).Set(n)
+	result := 1
+
+	for a.Sign() != 0 {
+		// (2/n) = -1 when n = 3 or 5 mod 8
+		for a.Bit(0) == 0 {
+			a.Rsh(a, 1)
+			if mod8 := n.Bit(2)<<2 | n.Bit(1)<<1 | n.Bit(0); mod8 == 3 || mod8 == 5 {
+				result = -result
+			}
+		}
+
+		// Quadratic reciprocity flips the sign when both are 3 mod 4
+		a, n = n, a
+		if a.Bit(1) == 1 && n.Bit(1) == 1 {
+			result = -result
+		}
+		a.Mod(a, n)
+	}
+
+	if n.Cmp(big.NewInt(1)) != 0 {
+		return 0
+	}
+
+	return result
+}
@@ -0,0 +1,57 @@
+package numtheory
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestJacobi(t *testing.T) {
+	tests := []struct {
+		a, n   int64
+		result int
+	}{
+		{1, 1, 1},
+		{0, 1, 1},
+		{0, 3, 0},
+		{1, 3, 1},
+		{2, 3, -1},
+		{2, 7, 1},
+		{3, 7, -1},
+		{5, 21, 1},
+		{7, 21, 0},
+		{19, 45, 1},
+		{8, 21, -1},
+		{1001, 9907, -1},
+		{-1, 7, -1},
+		{-1, 13, 1},
+		{30, 59, -1},
+	}
+
+	for _, test := range tests {
+		if result := Jacobi(big.NewInt(test.a), big.NewInt(test.n)); result != test.result {
+			t.Errorf("Jacobi(%d, %d) = %d, expected %d", test.a, test.n, result, test.result)
+		}
+	}
+
+	// Agrees with math/big
+	for n := int64(1); n < 200; n += 2 {
+		for a := int64(-50); a < 250; a++ {
+			if Jacobi(big.NewInt(a), big.NewInt(n)) != big.Jacobi(big.NewInt(a), big.NewInt(n)) {
+				t.Fatalf("Jacobi(%d, %d) does not match math/big", a, n)
+			}
+		}
+	}
+}
+
+func TestJacobiPanics(t *testing.T) {
+	for _, n := range []int64{0, 4, -3} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Jacobi(1, %d) did not panic", n)
+				}
+			}()
+			Jacobi(big.NewInt(1), big.NewInt(n))
+		}()
+	}
+}
@@ -0,0 +1,280 @@
+package numtheory
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// RandomBetween returns a uniform random number in [a, b)
+func RandomBetween(random io.Reader, a, b *big.Int) (*big.Int, error) {
+	if a.Cmp(b) >= 0 {
+		return nil, errors.New("empty range")
+	}
+
+	n, err := rand.Int(random, new(big.Int).Sub(b, a))
+	if err != nil {
+		return nil, err
+	}
+
+	return n.Add(n, a), nil
+}
+
+// SmallPrimes lists the primes below limit using the sieve of Eratosthenes
+func SmallPrimes(limit uint64) []uint64 {
+	composite := make([]bool, limit)
+	var primes []uint64
+	for i := uint64(2); i < limit; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, i)
+		for j := i * i; j < limit; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}
+
+// Primes used for trial division before the expensive tests
+var trialPrimes = SmallPrimes(1000)
+
+// Whether n has a prime factor below 1000 other than itself
+func hasSmallFactor(n *big.Int) bool {
+	m := new(big.Int)
+	for _, p := range trialPrimes {
+		if n.IsUint64() && n.Uint64() == p {
+			return false
+		}
+
+		if m.Mod(n, m.SetUint64(p)).Sign() == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StrongProbablePrime is one round of Miller-Rabin: is the odd number n > 3 a strong probable prime to base a
+// Implemented using psuedo code from
+// https://en.wikipedia.org/wiki/Miller%E2%80%93Rabin_primality_test#Miller%E2%80%93Rabin_test
+func StrongProbablePrime(n, a *big.Int) bool {
+	one := big.NewInt(1)
+	two := big.NewInt(2)
+	nm1 := new(big.Int).Sub(n, one)
+
+	// write n as 2^r·d + 1 with d odd
+	d := new(big.Int).Set(nm1)
+	r := uint(0)
+	for d.Bit(0) == 0 {
+		d.Rsh(d, 1)
+		r++
+	}
+
+	// x ← a^d mod n
+	x := new(big.Int).Exp(a, d, n)
+
+	// if x = 1 or x = n − 1 then
+	if x.Cmp(one) == 0 || x.Cmp(nm1) == 0 {
+		return true
+	}
+
+	// repeat r − 1 times:
+	for j := uint(0); j < r-1; j++ {
+		// x ← x^2 mod n
+		x.Exp(x, two, n)
+
+		// if x = n − 1 then
+		if x.Cmp(nm1) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MillerRabin tests n with k random bases from crypto/rand, a composite passes with probability at most 4^-k
+func MillerRabin(n *big.Int, k int) bool {
+	// Miller-Rabin needs n > 3, so small and even numbers are handled first
+	if n.Cmp(big.NewInt(4)) < 0 {
+		return n.Cmp(big.NewInt(2)) >= 0
+	}
+
+	if n.Bit(0) == 0 {
+		return false
+	}
+
+	// WitnessLoop
+	two := big.NewInt(2)
+	nm1 := new(big.Int).Sub(n, big.NewInt(1))
+	for i := 0; i < k; i++ {
+		// pick a random integer a in the range [2, n − 2]
+		a, err := RandomBetween(rand.Reader, two, nm1)
+		if err != nil {
+			return false
+		}
+
+		if !StrongProbablePrime(n, a) {
+			// return “composite”
+			return false
+		}
+	}
+
+	// return “probably prime”
+	return true
+}
+
+// Halves x mod the odd number n
+func halveMod(x, n *big.Int) *big.Int {
+	if x.Bit(0) == 1 {
+		x.Add(x, n)
+	}
+	return x.Rsh(x, 1)
+}
+
+// StrongLucasProbablePrime is the strong Lucas probable prime test for an odd n > 2 with parameters from Selfridge's method A
+// Implemented from section 3 of Baillie & Wagstaff, "Lucas Pseudoprimes" (1980)
+// https://en.wikipedia.org/wiki/Lucas_pseudoprime#Strong_Lucas_pseudoprimes
+func StrongLucasProbablePrime(n *big.Int) bool {
+	// A square never has (D/n) = -1, so the search below wouldn't end
+	if s := new(big.Int).Sqrt(n); s.Mul(s, s).Cmp(n) == 0 {
+		return false
+	}
+
+	// D is the first of 5, -7, 9, -11, ... with (D/n) = -1
+	D := big.NewInt(5)
+	abs := new(big.Int)
+	for {
+		j := Jacobi(D, n)
+		if j == -1 {
+			break
+		}
+
+		// D shares a factor with n
+		if j == 0 && abs.Abs(D).Cmp(n) != 0 {
+			return false
+		}
+
+		if D.Sign() > 0 {
+			D.Add(D, big.NewInt(2)).Neg(D)
+		} else {
+			D.Neg(D).Add(D, big.NewInt(2))
+		}
+	}
+
+	// P = 1, Q = (1 - D) / 4
+	Q := new(big.Int).Sub(big.NewInt(1), D)
+	Q.Quo(Q, big.NewInt(4)).Mod(Q, n)
+
+	// n + 1 = 2^s·d with d odd
+	d := new(big.Int).Add(n, big.NewInt(1))
+	s := uint(0)
+	for d.Bit(0) == 0 {
+		d.Rsh(d, 1)
+		s++
+	}
+
+	// Walk the bits of d from the top keeping U_k, V_k and Q^k, starting with k = 1
+	U := big.NewInt(1)
+	V := big.NewInt(1)
+	Qk := new(big.Int).Set(Q)
+	t := new(big.Int)
+
+	for i := d.BitLen() - 2; i >= 0; i-- {
+		// U_2k = U_k·V_k, V_2k = V_k^2 - 2Q^k
+		U.Mul(U, V).Mod(U, n)
+		V.Mul(V, V).Sub(V, t.Lsh(Qk, 1)).Mod(V, n)
+		Qk.Mul(Qk, Qk).Mod(Qk, n)
+
+		if d.Bit(i) == 1 {
+			// U_k+1 = (P·U_k + V_k) / 2, V_k+1 = (D·U_k + P·V_k) / 2
+			t.Mul(D, U).Add(t, V).Mod(t, n)
+			U.Add(U, V).Mod(U, n)
+			halveMod(U, n)
+			V.Set(halveMod(t, n))
+			Qk.Mul(Qk, Q).Mod(Qk, n)
+		}
+	}
+
+	// U_d = 0 or V_d = 0
+	if U.Sign() == 0 || V.Sign() == 0 {
+		return true
+	}
+
+	// V_(2^r·d) = 0 for some 0 < r < s
+	for r := uint(1); r < s; r++ {
+		V.Mul(V, V).Sub(V, t.Lsh(Qk, 1)).Mod(V, n)
+		if V.Sign() == 0 {
+			return true
+		}
+		Qk.Mul(Qk, Qk).Mod(Qk, n)
+	}
+
+	return false
+}
+
+// BailliePSW is Miller-Rabin with base 2 followed by a strong Lucas test.
+// It is deterministic and there is no known composite that passes it.
+func BailliePSW(n *big.Int) bool {
+	if n.Cmp(big.NewInt(4)) < 0 {
+		return n.Cmp(big.NewInt(2)) >= 0
+	}
+
+	if n.Bit(0) == 0 {
+		return false
+	}
+
+	return StrongProbablePrime(n, big.NewInt(2)) && StrongLucasProbablePrime(n)
+}
+
+// RandomPrime returns a prime of bits length with the top two bits set, so the product of two has exactly 2 * bits
+func RandomPrime(random io.Reader, bits int) (*big.Int, error) {
+	if bits < 2 {
+		return nil, errors.New("primes need at least 2 bits")
+	}
+
+	b := make([]byte, (bits+7)/8)
+	excess := 8*len(b) - bits
+
+	for {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return nil, err
+		}
+
+		// Clear the bits above bits, set the top two and the last
+		b[0] &= 0xff >> excess
+		if excess < 7 {
+			b[0] |= 0xc0 >> excess
+		} else {
+			b[0] |= 0x01
+			b[1] |= 0x80
+		}
+		b[len(b)-1] |= 0x01
+
+		candidate := new(big.Int).SetBytes(b)
+		if !hasSmallFactor(candidate) && BailliePSW(candidate) {
+			return candidate, nil
+		}
+	}
+}
+
+// SafePrime returns a prime p = 2q + 1 of bits length where q is also prime
+func SafePrime(random io.Reader, bits int) (p, q *big.Int, err error) {
+	if bits < 3 {
+		return nil, nil, errors.New("safe primes need at least 3 bits")
+	}
+
+	for {
+		q, err = RandomPrime(random, bits-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		p = new(big.Int).Lsh(q, 1)
+		p.Add(p, big.NewInt(1))
+		if !hasSmallFactor(p) && BailliePSW(p) {
+			return p, q, nil
+		}
+	}
+}
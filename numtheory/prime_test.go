@@ -0,0 +1,167 @@
+package numtheory
+
+import (
+	"crypto/rand"
+	"math/big"
+	mathrand "math/rand"
+	"testing"
+)
+
+func TestPrimalityTests(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(n *big.Int) bool
+	}{
+		{"MillerRabin", func(n *big.Int) bool { return MillerRabin(n, 20) }},
+		{"BailliePSW", BailliePSW},
+	}
+
+	for _, test := range tests {
+		// Agrees with math/big, which also runs Baillie-PSW
+		for i := int64(-5); i < 10000; i++ {
+			n := big.NewInt(i)
+			if test.test(n) != n.ProbablyPrime(0) {
+				t.Errorf("%s is wrong about %d", test.name, i)
+			}
+		}
+
+		for _, bits := range []int{64, 256, 1024} {
+			for i := 0; i < 20; i++ {
+				n, _ := rand.Prime(rand.Reader, bits)
+				if !test.test(n) {
+					t.Errorf("%s says the prime %s is composite", test.name, n)
+				}
+
+				// Odd and almost always composite
+				n.Add(n, big.NewInt(2))
+				if test.test(n) != n.ProbablyPrime(20) {
+					t.Errorf("%s is wrong about %s", test.name, n)
+				}
+			}
+		}
+	}
+}
+
+// Each half of Baillie-PSW catches the numbers that fool the other
+func TestBailliePSWPseudoprimes(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		fools  func(n *big.Int) bool
+	}{
+		{"Carmichael numbers", []int64{561, 41041, 825265}, func(n *big.Int) bool { return true }},
+		{"strong pseudoprimes to base 2", []int64{2047, 3277, 4033, 4681, 8321}, func(n *big.Int) bool { return StrongProbablePrime(n, big.NewInt(2)) }},
+		{"strong Lucas pseudoprimes", []int64{5459, 5777, 10877, 16109, 18971}, StrongLucasProbablePrime},
+	}
+
+	for _, test := range tests {
+		for _, v := range test.values {
+			n := big.NewInt(v)
+			if !test.fools(n) {
+				t.Errorf("%d is not one of the %s", v, test.name)
+			}
+
+			if BailliePSW(n) {
+				t.Errorf("Baillie-PSW says %d from the %s is prime", v, test.name)
+			}
+		}
+	}
+}
+
+func TestSmallPrimes(t *testing.T) {
+	tests := []struct {
+		limit uint64
+		count int
+	}{
+		{0, 0},
+		{2, 0},
+		{3, 1},
+		{100, 25},
+		{1000, 168},
+		{1 << 14, 1900},
+	}
+
+	for _, test := range tests {
+		primes := SmallPrimes(test.limit)
+		if len(primes) != test.count {
+			t.Errorf("SmallPrimes(%d) found %d primes, expected %d", test.limit, len(primes), test.count)
+		}
+
+		for _, p := range primes {
+			if !big.NewInt(int64(p)).ProbablyPrime(0) {
+				t.Fatalf("SmallPrimes(%d) returned %d", test.limit, p)
+			}
+		}
+	}
+}
+
+func TestRandomPrime(t *testing.T) {
+	random := mathrand.New(mathrand.NewSource(1))
+
+	for _, bits := range []int{2, 3, 8, 16, 17, 64, 512} {
+		p, err := RandomPrime(random, bits)
+		if err != nil {
+			t.Fatalf("RandomPrime(%d) failed: %s", bits, err)
+		}
+
+		if p.BitLen() != bits || !p.ProbablyPrime(20) {
+			t.Errorf("RandomPrime(%d) = %s is not a %d bit prime", bits, p, bits)
+		}
+
+		if bits >= 2 && p.Bit(bits-2) == 0 {
+			t.Errorf("RandomPrime(%d) = %s does not have its top two bits set", bits, p)
+		}
+	}
+
+	if _, err := RandomPrime(random, 1); err == nil {
+		t.Errorf("RandomPrime(1) did not fail")
+	}
+}
+
+func TestSafePrime(t *testing.T) {
+	random := mathrand.New(mathrand.NewSource(1))
+
+	for _, bits := range []int{3, 16, 128} {
+		p, q, err := SafePrime(random, bits)
+		if err != nil {
+			t.Fatalf("SafePrime(%d) failed: %s", bits, err)
+		}
+
+		if p.BitLen() != bits || !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+			t.Errorf("SafePrime(%d) = %s, %s are not primes", bits, p, q)
+		}
+
+		if new(big.Int).Add(new(big.Int).Lsh(q, 1), big.NewInt(1)).Cmp(p) != 0 {
+			t.Errorf("SafePrime(%d) = %s is not 2 * %s + 1", bits, p, q)
+		}
+	}
+}
+
+func TestRandomBetween(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		ok   bool
+	}{
+		{0, 1, true},
+		{2, 10, true},
+		{-5, 5, true},
+		{3, 3, false},
+		{5, 3, false},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			n, err := RandomBetween(rand.Reader, big.NewInt(test.a), big.NewInt(test.b))
+			if !test.ok {
+				if err == nil {
+					t.Errorf("RandomBetween(%d, %d) did not fail", test.a, test.b)
+				}
+				break
+			}
+
+			if err != nil || n.Int64() < test.a || n.Int64() >= test.b {
+				t.Fatalf("RandomBetween(%d, %d) = %v, %v", test.a, test.b, n, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package numtheory
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ErrNoSquareRoot
+// Error returned when a number is not a square mod p
+type ErrNoSquareRoot struct{}
+
+func (e ErrNoSquareRoot) Error() string {
+	return "number has no square root"
+}
+
+// ModSqrt finds x with x^2 = a mod p for an odd prime p using the Tonelli-Shanks algorithm
+// https://en.wikipedia.org/wiki/Tonelli%E2%80%93Shanks_algorithm
+func ModSqrt(a, p *big.Int) (*big.Int, error) {
+	one := big.NewInt(1)
+	two := big.NewInt(2)
+
+	if p.Cmp(two) <= 0 || p.Bit(0) == 0 {
+		return nil, fmt.Errorf("%w: %s is not an odd prime", ErrNoSquareRoot{}, p)
+	}
+
+	a = new(big.Int).Mod(a, p)
+	if a.Sign() == 0 {
+		return a, nil
+	}
+
+	if Jacobi(a, p) != 1 {
+		return nil, fmt.Errorf("%w: %s is not a square mod %s", ErrNoSquareRoot{}, a, p)
+	}
+
+	// p - 1 = Q * 2^S with Q odd
+	Q := new(big.Int).Sub(p, one)
+	S := 0
+	for Q.Bit(0) == 0 {
+		Q.Rsh(Q, 1)
+		S++
+	}
+
+	// z is any quadratic non-residue
+	z := big.NewInt(2)
+	for Jacobi(z, p) != -1 {
+		z.Add(z, one)
+		if z.Cmp(p) >= 0 {
+			return nil, fmt.Errorf("%w: %s is not prime", ErrNoSquareRoot{}, p)
+		}
+	}
+
+	M := S
+	c := new(big.Int).Exp(z, Q, p)
+	t := new(big.Int).Exp(a, Q, p)
+	R := new(big.Int).Exp(a, new(big.Int).Rsh(new(big.Int).Add(Q, one), 1), p)
+
+	for t.Cmp(one) != 0 {
+		// The least 0 < i < M with t^(2^i) = 1
+		i := 0
+		for x := new(big.Int).Set(t); x.Cmp(one) != 0; x.Mul(x, x).Mod(x, p) {
+			i++
+			if i == M {
+				return nil, fmt.Errorf("%w: %s is not prime", ErrNoSquareRoot{}, p)
+			}
+		}
+
+		// b = c^(2^(M-i-1))
+		b := new(big.Int).Set(c)
+		for j := 0; j < M-i-1; j++ {
+			b.Mul(b, b).Mod(b, p)
+		}
+
+		M = i
+		c.Mul(b, b).Mod(c, p)
+		t.Mul(t, c).Mod(t, p)
+		R.Mul(R, b).Mod(R, p)
+	}
+
+	// A composite p can slip through, so check the answer
+	if check := new(big.Int).Mul(R, R); check.Mod(check, p).Cmp(a) != 0 {
+		return nil, fmt.Errorf("%w: %s is not prime", ErrNoSquareRoot{}, p)
+	}
+
+	return R, nil
+}
@@ -0,0 +1,73 @@
+package numtheory
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestModSqrt(t *testing.T) {
+	// Primes that are 1 mod 4 take the full Tonelli-Shanks loop, 998244353 = 119 * 2^23 + 1 takes the longest
+	p25519 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	square := func(x int64, p *big.Int) *big.Int {
+		return new(big.Int).Exp(big.NewInt(x), big.NewInt(2), p)
+	}
+
+	tests := []struct {
+		a, p *big.Int
+		ok   bool
+	}{
+		{big.NewInt(0), big.NewInt(7), true},
+		{big.NewInt(2), big.NewInt(7), true},
+		{big.NewInt(4), big.NewInt(7), true},
+		{big.NewInt(3), big.NewInt(7), false},
+		{big.NewInt(5), big.NewInt(41), true},
+		{big.NewInt(10), big.NewInt(13), true},
+		{big.NewInt(-3), big.NewInt(13), true},
+		{big.NewInt(2), big.NewInt(113), true},
+		{big.NewInt(3), big.NewInt(113), false},
+		{big.NewInt(56), big.NewInt(101), true},
+		{big.NewInt(1030), big.NewInt(10009), true},
+		{square(123456789, p25519), p25519, true},
+		{square(123456789, big.NewInt(998244353)), big.NewInt(998244353), true},
+		{big.NewInt(3), big.NewInt(998244353), false},
+		{big.NewInt(2), p25519, false},
+		{big.NewInt(2), big.NewInt(2), false},
+		{big.NewInt(2), big.NewInt(8), false},
+		{big.NewInt(4), big.NewInt(15), false},
+	}
+
+	for _, test := range tests {
+		x, err := ModSqrt(test.a, test.p)
+		if !test.ok {
+			if !errors.As(err, &ErrNoSquareRoot{}) {
+				t.Errorf("ModSqrt(%s, %s) expected ErrNoSquareRoot, got %v", test.a, test.p, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ModSqrt(%s, %s) failed: %s", test.a, test.p, err)
+			continue
+		}
+
+		square := new(big.Int).Mul(x, x)
+		if square.Mod(square, test.p).Cmp(new(big.Int).Mod(test.a, test.p)) != 0 {
+			t.Errorf("ModSqrt(%s, %s) = %s is not a square root", test.a, test.p, x)
+		}
+	}
+
+	// Every square mod a small prime
+	p := big.NewInt(1009)
+	for x := int64(0); x < 1009; x++ {
+		a := big.NewInt(x * x % 1009)
+		root, err := ModSqrt(a, p)
+		if err != nil {
+			t.Fatalf("ModSqrt(%s, 1009) failed: %s", a, err)
+		}
+
+		if root.Int64()*root.Int64()%1009 != a.Int64() {
+			t.Fatalf("ModSqrt(%s, 1009) = %s is not a square root", a, root)
+		}
+	}
+}
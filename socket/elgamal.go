@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
 )
 
 // Unlike AES ElGamal is a private/public key system
@@ -22,13 +24,16 @@ type ElGamalPublicKey struct {
 	g *big.Int
 	// The public key, h = g^a mod p
 	h *big.Int
+
+	// Optional fixed-base tables for g and h, see Precompute
+	gTable, hTable *fixedBase
 }
 
 // Geneates a new ElGamal key pair.
 // Takes size of the prime as an argument in bits.
 func Keygen(keysize int) (*ElGamalPrivateKey, *ElGamalPublicKey) {
 	// generate a random prime
-	p, err := rand.Prime(rand.Reader, keysize)
+	p, err := numtheory.RandomPrime(rand.Reader, keysize)
 	if err != nil {
 		panic("could not generate random prime")
 	}
@@ -49,7 +54,7 @@ func Keygen(keysize int) (*ElGamalPrivateKey, *ElGamalPublicKey) {
 	h := new(big.Int).Exp(g, a, p)
 
 	// create the private key
-	private := &ElGamalPrivateKey{a, &ElGamalPublicKey{p, g, h}}
+	private := &ElGamalPrivateKey{a: a, public: &ElGamalPublicKey{p: p, g: g, h: h}}
 
 	return private, private.public
 }
@@ -80,10 +85,10 @@ func (pk *ElGamalPublicKey) _encrypt(m *big.Int) (ElGamalCipherText, error) {
 	}
 
 	// compute fullmask (g^a)^b mod p
-	fullmask := new(big.Int).Exp(pk.h, b, pk.p)
+	fullmask := pk.expH(b)
 
 	// compute shared secret g^b mod p
-	shared := new(big.Int).Exp(pk.g, b, pk.p)
+	shared := pk.expG(b)
 
 	// compute the ciphertext
 	ciphertext := new(big.Int).Mul(fullmask, m)
@@ -95,7 +100,7 @@ func (pk *ElGamalPublicKey) _encrypt(m *big.Int) (ElGamalCipherText, error) {
 // Encrypts a full message using ElGamal. Depending on the size of the message multiple messages will be returned.
 func (pk *ElGamalPublicKey) Encrypt(message []byte) ([]*ElGamalCipherText, error) {
 	// calculate the block size
-	bs := (pk.p.BitLen() / 8) - 1
+	bs := pk.blockSize()
 
 	// check if the message is not divisible by the block size
 	lastBlockSize := len(message) % bs
@@ -148,7 +153,10 @@ func (sk *ElGamalPrivateKey) _decrypt(cipher *ElGamalCipherText) (*big.Int, erro
 	fullmask := new(big.Int).Exp(cipher.shared, sk.a, sk.public.p)
 
 	// compute the modular inverse of the full mask
-	fullmaskInv := fullmask.ModInverse(fullmask, sk.public.p)
+	fullmaskInv, err := numtheory.ModInverse(fullmask, sk.public.p)
+	if err != nil {
+		return nil, fmt.Errorf("shared secret is not invertible: %w", err)
+	}
 
 	// compute the decrypted message
 	plaintext := new(big.Int).Mul(cipher.ciphertext, fullmaskInv)
@@ -167,21 +175,34 @@ func (sk *ElGamalPrivateKey) Decrypt(ciphers []*ElGamalCipherText) ([]byte, erro
 			return nil, err
 		}
 
-		b := make([]byte, 0, cipher.size)
-
-		// check if the plaintext is smaller than the block size
-		missingBytes := cipher.size - len(p.Bytes())
-		if missingBytes > 0 {
-			for i := 0; i < missingBytes; i++ {
-				b = append(b, 0)
-			}
-		} else if missingBytes < 0 {
-			return nil, fmt.Errorf("ciphertext too large")
+		b, err := sk.public.blockBytes(p, cipher.size)
+		if err != nil {
+			return nil, err
 		}
 
-		b = append(b, p.Bytes()...)
-		plaintext = append(plaintext, b[:cipher.size]...)
+		plaintext = append(plaintext, b...)
 	}
 
 	return plaintext, nil
 }
+
+// The number of plaintext bytes in a block, every block is smaller than p
+func (pk *ElGamalPublicKey) blockSize() int {
+	return (pk.p.BitLen() / 8) - 1
+}
+
+// Converts a decrypted block back into size bytes
+// size comes from the ciphertext, so it is checked before anything is allocated.
+// Encrypt's blocks hold at most blockSize bytes but a product from Mul can be as large as p.
+func (pk *ElGamalPublicKey) blockBytes(m *big.Int, size int) ([]byte, error) {
+	if size < 0 || size > pk.byteLen() {
+		return nil, fmt.Errorf("block size %d is out of range, blocks hold at most %d bytes", size, pk.byteLen())
+	}
+
+	if len(m.Bytes()) > size {
+		return nil, fmt.Errorf("ciphertext too large")
+	}
+
+	b := make([]byte, size)
+	return m.FillBytes(b), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/Alextopher/crypto/fingerprint"
+)
+
+// Appends a number prefixed with its length in 4 bytes, so a list of numbers is unambiguous
+func appendInt(b []byte, n *big.Int) []byte {
+	bytes := n.Bytes()
+	b = append(b, byte(len(bytes)>>24), byte(len(bytes)>>16), byte(len(bytes)>>8), byte(len(bytes)))
+	return append(b, bytes...)
+}
+
+// Encodes the public key as p, g and h, each prefixed with its length
+func (pk *ElGamalPublicKey) marshal() []byte {
+	b := appendInt(nil, pk.p)
+	b = appendInt(b, pk.g)
+	return appendInt(b, pk.h)
+}
+
+// Fingerprint is the SHA-256 hash of p, g and h, so two people can check they have the same key
+func (pk *ElGamalPublicKey) Fingerprint() fingerprint.Fingerprint {
+	return fingerprint.Of(pk.marshal())
+}
+
+// Inspect prints the size of the prime, the fingerprint and its randomart
+func (pk *ElGamalPublicKey) Inspect(w io.Writer) {
+	f := pk.Fingerprint()
+
+	fmt.Fprintln(w, "Type:        ElGamal public key")
+	fmt.Fprintf(w, "Prime:       %d bits\n", pk.p.BitLen())
+	fmt.Fprintf(w, "Fingerprint: %s\n", f)
+	fmt.Fprint(w, f.Randomart(fmt.Sprintf("ELGAMAL %d", pk.p.BitLen())))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestElGamalFingerprint(t *testing.T) {
+	_, public := Keygen(128)
+
+	// The fingerprint only depends on p, g and h
+	copied := &ElGamalPublicKey{p: new(big.Int).Set(public.p), g: new(big.Int).Set(public.g), h: new(big.Int).Set(public.h)}
+	if public.Fingerprint() != copied.Fingerprint() {
+		t.Errorf("Copies of a key have different fingerprints")
+	}
+
+	// Changing any of them changes it
+	for _, n := range []*big.Int{copied.p, copied.g, copied.h} {
+		n.Add(n, big.NewInt(1))
+		if public.Fingerprint() == copied.Fingerprint() {
+			t.Errorf("Different keys have the same fingerprint")
+		}
+		n.Sub(n, big.NewInt(1))
+	}
+
+	// The length prefixes keep numbers from running into each other
+	a := &ElGamalPublicKey{p: big.NewInt(0x0102), g: big.NewInt(0x03), h: big.NewInt(1)}
+	b := &ElGamalPublicKey{p: big.NewInt(0x01), g: big.NewInt(0x0203), h: big.NewInt(1)}
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Errorf("Keys with the same bytes in a different split have the same fingerprint")
+	}
+}
+
+func TestElGamalInspect(t *testing.T) {
+	_, public := Keygen(128)
+
+	var out bytes.Buffer
+	public.Inspect(&out)
+
+	for _, expected := range []string{"ElGamal public key", "128 bits", public.Fingerprint().String(), "[ELGAMAL 128]"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Inspect does not print %q:\n%s", expected, out.String())
+		}
+	}
+}
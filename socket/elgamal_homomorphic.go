@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// ElGamal is multiplicatively homomorphic
+// (g^b1, m1 * h^b1) * (g^b2, m2 * h^b2) = (g^(b1+b2), m1 * m2 * h^(b1+b2))
+// which is a valid encryption of m1 * m2 under the same key.
+//
+// Exponential ElGamal encrypts g^m instead of m, turning that product into a sum of the exponents.
+// Decrypting gives back g^m, so recovering m needs a discrete log which is only feasible for small m.
+
+// Makes sure a ciphertext can be combined with others under pk
+func (pk *ElGamalPublicKey) checkCipherText(c *ElGamalCipherText) error {
+	if c == nil || c.shared == nil || c.ciphertext == nil {
+		return fmt.Errorf("incomplete ciphertext")
+	}
+
+	if c.shared.Sign() <= 0 || c.shared.Cmp(pk.p) >= 0 {
+		return fmt.Errorf("shared secret out of range")
+	}
+
+	if c.ciphertext.Sign() < 0 || c.ciphertext.Cmp(pk.p) >= 0 {
+		return fmt.Errorf("ciphertext out of range")
+	}
+
+	return nil
+}
+
+// Mul combines encryptions of m1 and m2 into an encryption of m1 * m2 mod p.
+// The product can be as large as p, so Decrypt returns it zero padded to the byte length of p.
+func (pk *ElGamalPublicKey) Mul(c1, c2 *ElGamalCipherText) (*ElGamalCipherText, error) {
+	if err := pk.checkCipherText(c1); err != nil {
+		return nil, err
+	}
+
+	if err := pk.checkCipherText(c2); err != nil {
+		return nil, err
+	}
+
+	shared := new(big.Int).Mul(c1.shared, c2.shared)
+	shared.Mod(shared, pk.p)
+
+	ciphertext := new(big.Int).Mul(c1.ciphertext, c2.ciphertext)
+	ciphertext.Mod(ciphertext, pk.p)
+
+	return &ElGamalCipherText{shared, ciphertext, pk.byteLen()}, nil
+}
+
+// Rerandomize returns a fresh encryption of the same message by multiplying in an encryption of 1.
+// The result can't be linked to c without the private key.
+func (pk *ElGamalPublicKey) Rerandomize(c *ElGamalCipherText) (*ElGamalCipherText, error) {
+	if err := pk.checkCipherText(c); err != nil {
+		return nil, err
+	}
+
+	one, err := pk._encrypt(big.NewInt(1))
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := pk.Mul(c, &one)
+	if err != nil {
+		return nil, err
+	}
+
+	r.size = c.size
+	return r, nil
+}
+
+// EncryptExp encrypts g^m, so that Add on the ciphertexts adds the messages.
+// m must be small enough for DecryptExp to find it again.
+func (pk *ElGamalPublicKey) EncryptExp(m int64) (*ElGamalCipherText, error) {
+	if m < 0 {
+		return nil, fmt.Errorf("message must not be negative")
+	}
+
+	c, err := pk._encrypt(pk.expG(big.NewInt(m)))
+	if err != nil {
+		return nil, err
+	}
+
+	c.size = pk.byteLen()
+	return &c, nil
+}
+
+// Add combines exponential encryptions of m1 and m2 into an exponential encryption of m1 + m2
+func (pk *ElGamalPublicKey) Add(c1, c2 *ElGamalCipherText) (*ElGamalCipherText, error) {
+	return pk.Mul(c1, c2)
+}
+
+// DecryptExp decrypts a ciphertext made by EncryptExp (or sums of them) and
+// searches for the message in the range [0, max] with baby-step giant-step.
+func (sk *ElGamalPrivateKey) DecryptExp(c *ElGamalCipherText, max int64) (int64, error) {
+	if err := sk.public.checkCipherText(c); err != nil {
+		return 0, err
+	}
+
+	gm, err := sk._decrypt(c)
+	if err != nil {
+		return 0, err
+	}
+
+	return babyStepGiantStep(sk.public.g, gm, sk.public.p, max)
+}
+
+// Finds 0 <= x <= max such that g^x = y mod p in O(sqrt(max)) time and memory
+func babyStepGiantStep(g, y, p *big.Int, max int64) (int64, error) {
+	if max < 0 {
+		return 0, fmt.Errorf("max must not be negative")
+	}
+
+	// m * m > max, so every x <= max is i*m + j for some i, j < m
+	m := new(big.Int).Sqrt(big.NewInt(max)).Int64() + 1
+
+	// Baby steps: g^j for 0 <= j < m
+	table := make(map[string]int64, m)
+	gj := big.NewInt(1)
+	for j := int64(0); j < m; j++ {
+		key := string(gj.Bytes())
+		if _, ok := table[key]; !ok {
+			table[key] = j
+		}
+		gj = new(big.Int).Mul(gj, g)
+		gj.Mod(gj, p)
+	}
+
+	// Giant steps: y * g^(-m*i) for 0 <= i < m
+	factor, err := numtheory.ModInverse(gj, p)
+	if err != nil {
+		return 0, fmt.Errorf("generator is not invertible mod p: %w", err)
+	}
+
+	gamma := new(big.Int).Mod(y, p)
+	for i := int64(0); i < m; i++ {
+		if j, ok := table[string(gamma.Bytes())]; ok {
+			x := i*m + j
+			if x > max {
+				break
+			}
+			return x, nil
+		}
+		gamma.Mul(gamma, factor)
+		gamma.Mod(gamma, p)
+	}
+
+	return 0, fmt.Errorf("discrete log not found in [0, %d]", max)
+}
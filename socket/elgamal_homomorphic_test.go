@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestElGamalMul(t *testing.T) {
+	private, public := Keygen(256)
+
+	for i := 0; i < 20; i++ {
+		m1 := big.NewInt(rand.Int63())
+		m2 := big.NewInt(rand.Int63())
+
+		c1, err := public._encrypt(m1)
+		if err != nil {
+			t.Fatalf("Could not encrypt m1: %s", err)
+		}
+
+		c2, err := public._encrypt(m2)
+		if err != nil {
+			t.Fatalf("Could not encrypt m2: %s", err)
+		}
+
+		product, err := public.Mul(&c1, &c2)
+		if err != nil {
+			t.Fatalf("Could not multiply ciphertexts: %s", err)
+		}
+
+		plaintext, err := private._decrypt(product)
+		if err != nil {
+			t.Fatalf("Could not decrypt product: %s", err)
+		}
+
+		expected := new(big.Int).Mul(m1, m2)
+		expected.Mod(expected, public.p)
+		if plaintext.Cmp(expected) != 0 {
+			t.Errorf("%d * %d decrypted to %d, expected %d", m1, m2, plaintext, expected)
+		}
+	}
+}
+
+func TestElGamalMulRejectsBadCipherText(t *testing.T) {
+	_, public := Keygen(256)
+
+	c, err := public._encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("Could not encrypt: %s", err)
+	}
+
+	bad := []*ElGamalCipherText{
+		nil,
+		{},
+		{shared: big.NewInt(0), ciphertext: c.ciphertext},
+		{shared: c.shared, ciphertext: public.p},
+	}
+
+	for i, b := range bad {
+		if _, err := public.Mul(&c, b); err == nil {
+			t.Errorf("Expected an error for bad ciphertext %d", i)
+		}
+	}
+}
+
+func TestElGamalRerandomize(t *testing.T) {
+	private, public := Keygen(256)
+	message := []byte("Rerandomize me")
+
+	ciphers, err := public.Encrypt(message)
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+
+	rerandomized := make([]*ElGamalCipherText, len(ciphers))
+	for i, c := range ciphers {
+		rerandomized[i], err = public.Rerandomize(c)
+		if err != nil {
+			t.Fatalf("Could not rerandomize: %s", err)
+		}
+
+		if rerandomized[i].shared.Cmp(c.shared) == 0 || rerandomized[i].ciphertext.Cmp(c.ciphertext) == 0 {
+			t.Errorf("Rerandomized ciphertext is unchanged")
+		}
+	}
+
+	plaintext, err := private.Decrypt(rerandomized)
+	if err != nil {
+		t.Fatalf("Could not decrypt message: %s", err)
+	}
+
+	if string(plaintext) != string(message) {
+		t.Errorf("Rerandomized message decrypted to %q", plaintext)
+	}
+}
+
+// Products and their rerandomizations decrypt through the public API
+func TestElGamalMulDecrypt(t *testing.T) {
+	private, public := Keygen(256)
+
+	for i := 0; i < 20; i++ {
+		m1 := make([]byte, 1+rand.Intn(public.blockSize()))
+		m2 := make([]byte, 1+rand.Intn(public.blockSize()))
+		rand.Read(m1)
+		rand.Read(m2)
+
+		c1, err := public.Encrypt(m1)
+		if err != nil {
+			t.Fatalf("Could not encrypt m1: %s", err)
+		}
+
+		c2, err := public.Encrypt(m2)
+		if err != nil {
+			t.Fatalf("Could not encrypt m2: %s", err)
+		}
+
+		product, err := public.Mul(c1[0], c2[0])
+		if err != nil {
+			t.Fatalf("Could not multiply ciphertexts: %s", err)
+		}
+
+		rerandomized, err := public.Rerandomize(product)
+		if err != nil {
+			t.Fatalf("Could not rerandomize product: %s", err)
+		}
+
+		expected := new(big.Int).Mul(new(big.Int).SetBytes(m1), new(big.Int).SetBytes(m2))
+		expected.Mod(expected, public.p)
+		expectedBytes := expected.FillBytes(make([]byte, public.byteLen()))
+
+		for _, c := range []*ElGamalCipherText{product, rerandomized} {
+			plaintext, err := private.Decrypt([]*ElGamalCipherText{c})
+			if err != nil {
+				t.Fatalf("Could not decrypt product: %s", err)
+			}
+
+			if !bytes.Equal(plaintext, expectedBytes) {
+				t.Errorf("%x * %x decrypted to %x, expected %x", m1, m2, plaintext, expectedBytes)
+			}
+		}
+	}
+}
+
+func TestBabyStepGiantStep(t *testing.T) {
+	_, public := Keygen(256)
+
+	for _, x := range []int64{0, 1, 2, 99, 100, 1000, 4095} {
+		y := new(big.Int).Exp(public.g, big.NewInt(x), public.p)
+
+		got, err := babyStepGiantStep(public.g, y, public.p, 4095)
+		if err != nil {
+			t.Errorf("Could not find log of g^%d: %s", x, err)
+			continue
+		}
+
+		if got != x {
+			t.Errorf("log of g^%d = %d", x, got)
+		}
+	}
+
+	// Out of range
+	y := new(big.Int).Exp(public.g, big.NewInt(5000), public.p)
+	if _, err := babyStepGiantStep(public.g, y, public.p, 4095); err == nil {
+		t.Errorf("Expected an error when the log is out of range")
+	}
+}
+
+// A yes/no vote where every ballot is an exponential encryption of 0 or 1.
+// The tally is computed without decrypting any single ballot.
+func TestElGamalTally(t *testing.T) {
+	private, public := Keygen(512)
+	public.Precompute(DefaultWindow)
+
+	const voters = 200
+	expected := int64(0)
+
+	var tally *ElGamalCipherText
+	for i := 0; i < voters; i++ {
+		vote := int64(rand.Intn(2))
+		expected += vote
+
+		ballot, err := public.EncryptExp(vote)
+		if err != nil {
+			t.Fatalf("Could not encrypt ballot: %s", err)
+		}
+
+		// Ballots are mixed before they are counted
+		ballot, err = public.Rerandomize(ballot)
+		if err != nil {
+			t.Fatalf("Could not rerandomize ballot: %s", err)
+		}
+
+		if tally == nil {
+			tally = ballot
+			continue
+		}
+
+		tally, err = public.Add(tally, ballot)
+		if err != nil {
+			t.Fatalf("Could not add ballot: %s", err)
+		}
+	}
+
+	result, err := private.DecryptExp(tally, voters)
+	if err != nil {
+		t.Fatalf("Could not decrypt tally: %s", err)
+	}
+
+	if result != expected {
+		t.Errorf("Tally is %d, expected %d", result, expected)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/Alextopher/crypto/keyfile"
+)
+
+// ElGamal private keys are saved as a DER SEQUENCE of p, g, h and a in a PEM "ELGAMAL PRIVATE KEY" block,
+// or encrypted under a passphrase as a PEM "ENCRYPTED PRIVATE KEY"
+const elGamalPEMType = "ELGAMAL PRIVATE KEY"
+
+type elGamalPrivateKeyDER struct {
+	P, G, H, A *big.Int
+}
+
+// Encodes the private key as DER
+func (sk *ElGamalPrivateKey) marshal() ([]byte, error) {
+	return asn1.Marshal(elGamalPrivateKeyDER{sk.public.p, sk.public.g, sk.public.h, sk.a})
+}
+
+// Decodes a DER private key and checks h = g^a mod p
+func parseElGamalPrivateKey(der []byte) (*ElGamalPrivateKey, error) {
+	var key elGamalPrivateKeyDER
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, fmt.Errorf("malformed ElGamal private key: %w", err)
+	}
+
+	if len(rest) > 0 {
+		return nil, errors.New("malformed ElGamal private key: trailing data")
+	}
+
+	one := big.NewInt(1)
+	if key.P.Cmp(one) <= 0 {
+		return nil, errors.New("malformed ElGamal private key: p must be greater than 1")
+	}
+
+	for _, x := range []*big.Int{key.G, key.H, key.A} {
+		if x.Sign() <= 0 || x.Cmp(key.P) >= 0 {
+			return nil, errors.New("malformed ElGamal private key: g, h and a must be between 0 and p")
+		}
+	}
+
+	if new(big.Int).Exp(key.G, key.A, key.P).Cmp(key.H) != 0 {
+		return nil, errors.New("inconsistent ElGamal private key: h != g^a mod p")
+	}
+
+	return &ElGamalPrivateKey{a: key.A, public: &ElGamalPublicKey{p: key.P, g: key.G, h: key.H}}, nil
+}
+
+// SavePEM writes the private key as PEM, encrypted under the passphrase unless it is empty
+func (sk *ElGamalPrivateKey) SavePEM(w io.Writer, passphrase []byte) error {
+	der, err := sk.marshal()
+	if err != nil {
+		return err
+	}
+
+	if len(passphrase) == 0 {
+		return pem.Encode(w, &pem.Block{Type: elGamalPEMType, Bytes: der})
+	}
+	return keyfile.EncryptPEM(w, der, passphrase, keyfile.DefaultParams)
+}
+
+// ParseElGamalPrivateKey reads a private key written by SavePEM, the passphrase is only used when it is encrypted
+func ParseElGamalPrivateKey(data, passphrase []byte) (*ElGamalPrivateKey, error) {
+	if keyfile.IsEncrypted(data) {
+		der, err := keyfile.DecryptPEM(data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return parseElGamalPrivateKey(der)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != elGamalPEMType {
+		return nil, fmt.Errorf("expected a PEM %q block", elGamalPEMType)
+	}
+
+	return parseElGamalPrivateKey(block.Bytes)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Every ElGamal encryption computes g^b and h^b mod p for a fresh b, but g and h never change for a public key.
+// A fixed-base table stores base^(j * 2^(window*i)) mod p for every row i and digit j, so that
+// base^e is just a product of one table entry for each window sized digit of e.
+// For a 2048 bit prime and a window of 4 that is 512 multiplications instead of a full square and multiply.
+
+// The default window size used by Precompute
+const DefaultWindow = 4
+
+type fixedBase struct {
+	// The modulus
+	p *big.Int
+
+	// The number of exponent bits handled by each row
+	window uint
+
+	// rows[i][j] = base^(j * 2^(window*i)) mod p
+	rows [][]*big.Int
+}
+
+// Builds the table for base that handles exponents of up to bits bits
+func newFixedBase(base, p *big.Int, bits int, window uint) *fixedBase {
+	fb := &fixedBase{
+		p:      p,
+		window: window,
+		rows:   make([][]*big.Int, (uint(bits)+window-1)/window),
+	}
+
+	// b = base^(2^(window*i)) mod p for the current row
+	b := new(big.Int).Mod(base, p)
+	for i := range fb.rows {
+		row := make([]*big.Int, 1<<window)
+		row[0] = big.NewInt(1)
+		for j := 1; j < len(row); j++ {
+			row[j] = new(big.Int).Mul(row[j-1], b)
+			row[j].Mod(row[j], p)
+		}
+		fb.rows[i] = row
+
+		// b^(2^window) is the next entry after the end of the row
+		b = new(big.Int).Mul(row[len(row)-1], b)
+		b.Mod(b, p)
+	}
+
+	return fb
+}
+
+// Computes base^e mod p using the table
+func (fb *fixedBase) exp(e *big.Int) *big.Int {
+	result := big.NewInt(1)
+
+	// Exponents the table wasn't built for are handled without it
+	if e.Sign() < 0 || uint(e.BitLen()) > uint(len(fb.rows))*fb.window {
+		return result.Exp(fb.rows[0][1], e, fb.p)
+	}
+
+	for i, row := range fb.rows {
+		// Read the i-th digit of e
+		digit := uint(0)
+		for k := uint(0); k < fb.window; k++ {
+			digit |= e.Bit(int(uint(i)*fb.window+k)) << k
+		}
+
+		if digit != 0 {
+			result.Mul(result, row[digit])
+			result.Mod(result, fb.p)
+		}
+	}
+
+	return result
+}
+
+// Precompute builds fixed-base tables for g and h which speed up every later call to Encrypt.
+// Each table holds (bits of p / window) * 2^window numbers, so a window of 4 and a 2048 bit prime
+// costs about 2MB per table. A window of 0 removes the tables.
+func (pk *ElGamalPublicKey) Precompute(window uint) {
+	if window == 0 {
+		pk.gTable, pk.hTable = nil, nil
+		return
+	}
+
+	// The random exponents used by _encrypt are always less than p
+	bits := pk.p.BitLen()
+	pk.gTable = newFixedBase(pk.g, pk.p, bits, window)
+	pk.hTable = newFixedBase(pk.h, pk.p, bits, window)
+}
+
+// Computes g^e mod p, using the precomputed table when there is one
+func (pk *ElGamalPublicKey) expG(e *big.Int) *big.Int {
+	if pk.gTable != nil {
+		return pk.gTable.exp(e)
+	}
+
+	return new(big.Int).Exp(pk.g, e, pk.p)
+}
+
+// Computes h^e mod p, using the precomputed table when there is one
+func (pk *ElGamalPublicKey) expH(e *big.Int) *big.Int {
+	if pk.hTable != nil {
+		return pk.hTable.exp(e)
+	}
+
+	return new(big.Int).Exp(pk.h, e, pk.p)
+}
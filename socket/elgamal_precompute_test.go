@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// The table must agree with big.Int.Exp for every window size
+func TestFixedBaseExp(t *testing.T) {
+	_, public := Keygen(256)
+
+	exponents := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(public.p, big.NewInt(1)),
+		// too large for the table, must fall back to Exp
+		new(big.Int).Lsh(public.p, 10),
+	}
+	for i := 0; i < 20; i++ {
+		e, _ := rand.Int(rand.Reader, public.p)
+		exponents = append(exponents, e)
+	}
+
+	for window := uint(1); window <= 8; window++ {
+		fb := newFixedBase(public.g, public.p, public.p.BitLen(), window)
+
+		for _, e := range exponents {
+			expected := new(big.Int).Exp(public.g, e, public.p)
+			if got := fb.exp(e); got.Cmp(expected) != 0 {
+				t.Errorf("window %d: g^%d = %d, expected %d", window, e, got, expected)
+			}
+		}
+	}
+}
+
+func TestElGamalPrecompute(t *testing.T) {
+	message := make([]byte, 1000)
+	rand.Read(message)
+
+	for _, window := range []uint{1, DefaultWindow, 7} {
+		t.Run(fmt.Sprintf("Window: %d", window), func(t *testing.T) {
+			private, public := Keygen(512)
+			public.Precompute(window)
+
+			ciphertext, err := public.Encrypt(message)
+			if err != nil {
+				t.Fatalf("Could not encrypt message: %s", err)
+			}
+
+			plaintext, err := private.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("Could not decrypt message: %s", err)
+			}
+
+			if string(plaintext) != string(message) {
+				t.Errorf("Decrypted message does not match original message")
+			}
+		})
+	}
+}
+
+func benchmarkEncryptBlock(b *testing.B, window uint) {
+	_, public := Keygen(2048)
+	public.Precompute(window)
+
+	m, _ := rand.Int(rand.Reader, public.p)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := public._encrypt(m); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkElGamalEncryptBlock2048(b *testing.B) { benchmarkEncryptBlock(b, 0) }
+
+func BenchmarkElGamalEncryptBlock2048Window4(b *testing.B) { benchmarkEncryptBlock(b, 4) }
+
+func BenchmarkElGamalEncryptBlock2048Window6(b *testing.B) { benchmarkEncryptBlock(b, 6) }
+
+func BenchmarkElGamalPrecompute2048(b *testing.B) {
+	_, public := Keygen(2048)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		public.Precompute(DefaultWindow)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// ElGamal and Schnorr signatures over the same (p, g) used for encryption.
+// The order of g isn't known, but it always divides p - 1, so exponents are reduced mod p - 1.
+// Nonces are derived from the private key and the message (RFC 6979) so they are never reused
+// for different messages, and signing the same message twice gives the same signature.
+
+// A signature (r, s) where r = g^k mod p for the nonce k
+type ElGamalSignature struct {
+	r, s *big.Int
+}
+
+// A signature (r, s) where r = g^k mod p for the nonce k
+type SchnorrSignature struct {
+	r, s *big.Int
+}
+
+// Sign creates an ElGamal signature over the SHA-256 hash of message
+// s = (H(m) - a*r) * k^-1 mod p-1
+func (sk *ElGamalPrivateKey) Sign(message []byte) (*ElGamalSignature, error) {
+	pm1 := new(big.Int).Sub(sk.public.p, big.NewInt(1))
+	digest := sha256.Sum256(message)
+	hm := new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), pm1)
+
+	nonces := newNonceGenerator(sha256.New, pm1, sk.a, digest[:])
+	for i := 0; i < maxNonceTries; i++ {
+		k := nonces.next()
+
+		// k must be invertible mod p-1
+		kInv, err := numtheory.ModInverse(k, pm1)
+		if err != nil {
+			continue
+		}
+
+		sig := sk.signElGamal(hm, k, kInv, pm1)
+		if sig.s.Sign() != 0 {
+			return sig, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not find a suitable nonce")
+}
+
+// Signs the hash hm with the nonce k
+func (sk *ElGamalPrivateKey) signElGamal(hm, k, kInv, pm1 *big.Int) *ElGamalSignature {
+	r := sk.public.expG(k)
+
+	// s = (H(m) - a*r) * k^-1 mod p-1
+	s := new(big.Int).Mul(sk.a, r)
+	s.Sub(hm, s)
+	s.Mul(s, kInv)
+	s.Mod(s, pm1)
+
+	return &ElGamalSignature{r, s}
+}
+
+// Verify checks an ElGamal signature by testing g^H(m) = h^r * r^s mod p
+func (pk *ElGamalPublicKey) Verify(message []byte, sig *ElGamalSignature) bool {
+	if sig == nil || sig.r == nil || sig.s == nil {
+		return false
+	}
+
+	pm1 := new(big.Int).Sub(pk.p, big.NewInt(1))
+	if sig.r.Sign() <= 0 || sig.r.Cmp(pk.p) >= 0 || sig.s.Sign() <= 0 || sig.s.Cmp(pm1) >= 0 {
+		return false
+	}
+
+	digest := sha256.Sum256(message)
+	hm := new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), pm1)
+
+	left := pk.expG(hm)
+
+	right := new(big.Int).Mul(pk.expH(sig.r), new(big.Int).Exp(sig.r, sig.s, pk.p))
+	right.Mod(right, pk.p)
+
+	return left.Cmp(right) == 0
+}
+
+// SignSchnorr creates a Schnorr signature over message
+// r = g^k, e = H(r || m), s = k + a*e mod p-1
+func (sk *ElGamalPrivateKey) SignSchnorr(message []byte) (*SchnorrSignature, error) {
+	pm1 := new(big.Int).Sub(sk.public.p, big.NewInt(1))
+	digest := sha256.Sum256(message)
+
+	nonces := newNonceGenerator(sha256.New, pm1, sk.a, digest[:])
+	return sk.signSchnorr(message, nonces.next()), nil
+}
+
+// Signs message with the nonce k
+func (sk *ElGamalPrivateKey) signSchnorr(message []byte, k *big.Int) *SchnorrSignature {
+	pm1 := new(big.Int).Sub(sk.public.p, big.NewInt(1))
+	r := sk.public.expG(k)
+	e := schnorrChallenge(r, message, pm1)
+
+	// s = k + a*e mod p-1
+	s := new(big.Int).Mul(sk.a, e)
+	s.Add(s, k)
+	s.Mod(s, pm1)
+
+	return &SchnorrSignature{r, s}
+}
+
+// VerifySchnorr checks a Schnorr signature by testing g^s = r * h^e mod p
+func (pk *ElGamalPublicKey) VerifySchnorr(message []byte, sig *SchnorrSignature) bool {
+	if sig == nil || sig.r == nil || sig.s == nil {
+		return false
+	}
+
+	pm1 := new(big.Int).Sub(pk.p, big.NewInt(1))
+	if sig.r.Sign() <= 0 || sig.r.Cmp(pk.p) >= 0 || sig.s.Sign() < 0 || sig.s.Cmp(pm1) >= 0 {
+		return false
+	}
+
+	e := schnorrChallenge(sig.r, message, pm1)
+
+	left := pk.expG(sig.s)
+
+	right := new(big.Int).Mul(sig.r, pk.expH(e))
+	right.Mod(right, pk.p)
+
+	return left.Cmp(right) == 0
+}
+
+// e = SHA-256(r || m) mod order
+func schnorrChallenge(r *big.Int, message []byte, order *big.Int) *big.Int {
+	h := sha256.New()
+	h.Write(r.Bytes())
+	h.Write(message)
+
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, order)
+}
+
+// Signing gives up if this many nonces in a row are unusable
+const maxNonceTries = 1000
+
+// Deterministic nonce generation following RFC 6979 section 3.2.
+// Every call to next continues the HMAC_DRBG so a rejected nonce is replaced by a new one.
+type nonceGenerator struct {
+	hash func() hash.Hash
+
+	// The order of the group, nonces are in [1, q)
+	q    *big.Int
+	qlen int
+
+	// HMAC_DRBG state
+	k, v []byte
+}
+
+func newNonceGenerator(h func() hash.Hash, q, x *big.Int, digest []byte) *nonceGenerator {
+	ng := &nonceGenerator{hash: h, q: q, qlen: q.BitLen()}
+
+	hlen := h().Size()
+	ng.v = bytes.Repeat([]byte{0x01}, hlen)
+	ng.k = make([]byte, hlen)
+
+	// The private key as int2octets(x) and the hash as bits2octets(h1)
+	seed := append(ng.int2octets(new(big.Int).Mod(x, q)), ng.bits2octets(digest)...)
+
+	ng.k = ng.mac(ng.v, []byte{0x00}, seed)
+	ng.v = ng.mac(ng.v)
+	ng.k = ng.mac(ng.v, []byte{0x01}, seed)
+	ng.v = ng.mac(ng.v)
+
+	return ng
+}
+
+// HMAC_K(parts...)
+func (ng *nonceGenerator) mac(parts ...[]byte) []byte {
+	m := hmac.New(ng.hash, ng.k)
+	for _, p := range parts {
+		m.Write(p)
+	}
+	return m.Sum(nil)
+}
+
+// Returns the next candidate nonce k with 1 <= k < q
+func (ng *nonceGenerator) next() *big.Int {
+	for {
+		t := make([]byte, 0, (ng.qlen+7)/8)
+		for len(t)*8 < ng.qlen {
+			ng.v = ng.mac(ng.v)
+			t = append(t, ng.v...)
+		}
+
+		k := ng.bits2int(t)
+
+		// Move the state forward so the next call gives a different candidate
+		ng.k = ng.mac(ng.v, []byte{0x00})
+		ng.v = ng.mac(ng.v)
+
+		if k.Sign() > 0 && k.Cmp(ng.q) < 0 {
+			return k
+		}
+	}
+}
+
+// Takes the leftmost qlen bits of b as an integer
+func (ng *nonceGenerator) bits2int(b []byte) *big.Int {
+	x := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - ng.qlen; excess > 0 {
+		x.Rsh(x, uint(excess))
+	}
+	return x
+}
+
+// x as a big endian number the byte length of q
+func (ng *nonceGenerator) int2octets(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, (ng.qlen+7)/8))
+}
+
+// bits2int(b) mod q as octets
+func (ng *nonceGenerator) bits2octets(b []byte) []byte {
+	z := ng.bits2int(b)
+	return ng.int2octets(z.Mod(z, ng.q))
+}
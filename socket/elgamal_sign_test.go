@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestElGamalSign(t *testing.T) {
+	for _, size := range []int{32, 128, 512} {
+		t.Run(fmt.Sprintf("Key Size: %d", size), func(t *testing.T) {
+			private, public := Keygen(size)
+			message := []byte("Hello World!")
+
+			sig, err := private.Sign(message)
+			if err != nil {
+				t.Fatalf("Could not sign message: %s", err)
+			}
+
+			if !public.Verify(message, sig) {
+				t.Errorf("Valid signature did not verify")
+			}
+
+			if public.Verify([]byte("Hello World?"), sig) {
+				t.Errorf("Signature verified for a different message")
+			}
+
+			tampered := &ElGamalSignature{sig.r, new(big.Int).Add(sig.s, big.NewInt(1))}
+			if public.Verify(message, tampered) {
+				t.Errorf("Tampered signature verified")
+			}
+
+			_, other := Keygen(size)
+			if other.Verify(message, sig) {
+				t.Errorf("Signature verified under a different key")
+			}
+		})
+	}
+}
+
+func TestSchnorrSign(t *testing.T) {
+	for _, size := range []int{32, 128, 512} {
+		t.Run(fmt.Sprintf("Key Size: %d", size), func(t *testing.T) {
+			private, public := Keygen(size)
+			public.Precompute(DefaultWindow)
+			message := []byte("Hello World!")
+
+			sig, err := private.SignSchnorr(message)
+			if err != nil {
+				t.Fatalf("Could not sign message: %s", err)
+			}
+
+			if !public.VerifySchnorr(message, sig) {
+				t.Errorf("Valid signature did not verify")
+			}
+
+			if public.VerifySchnorr([]byte("Hello World?"), sig) {
+				t.Errorf("Signature verified for a different message")
+			}
+
+			tampered := &SchnorrSignature{sig.r, new(big.Int).Add(sig.s, big.NewInt(1))}
+			if public.VerifySchnorr(message, tampered) {
+				t.Errorf("Tampered signature verified")
+			}
+		})
+	}
+}
+
+// Signing the same message twice gives the same signature,
+// and signing different messages never reuses a nonce (r = g^k)
+func TestSignatureNonces(t *testing.T) {
+	private, _ := Keygen(128)
+
+	elgamalR := make(map[string]int)
+	schnorrR := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		message := []byte(fmt.Sprintf("message %d", i))
+
+		sig, err := private.Sign(message)
+		if err != nil {
+			t.Fatalf("Could not sign message: %s", err)
+		}
+
+		again, _ := private.Sign(message)
+		if sig.r.Cmp(again.r) != 0 || sig.s.Cmp(again.s) != 0 {
+			t.Errorf("ElGamal signatures of message %d are not deterministic", i)
+		}
+
+		if j, ok := elgamalR[sig.r.String()]; ok {
+			t.Errorf("ElGamal nonce reused for messages %d and %d", j, i)
+		}
+		elgamalR[sig.r.String()] = i
+
+		schnorr, err := private.SignSchnorr(message)
+		if err != nil {
+			t.Fatalf("Could not sign message: %s", err)
+		}
+
+		if j, ok := schnorrR[schnorr.r.String()]; ok {
+			t.Errorf("Schnorr nonce reused for messages %d and %d", j, i)
+		}
+		schnorrR[schnorr.r.String()] = i
+	}
+}
+
+// Shows why nonce reuse is fatal: two Schnorr signatures with the same k give away the private key
+// s1 - s2 = a * (e1 - e2) mod p-1
+func TestSchnorrNonceReuseLeaksKey(t *testing.T) {
+	private, public := Keygen(128)
+	pm1 := new(big.Int).Sub(public.p, big.NewInt(1))
+	k := big.NewInt(123456789)
+
+	for i := 0; i < 100; i++ {
+		sig1 := private.signSchnorr([]byte(fmt.Sprintf("first %d", i)), k)
+		sig2 := private.signSchnorr([]byte(fmt.Sprintf("second %d", i)), k)
+
+		if sig1.r.Cmp(sig2.r) != 0 {
+			t.Fatalf("Same nonce should give the same r")
+		}
+
+		e1 := schnorrChallenge(sig1.r, []byte(fmt.Sprintf("first %d", i)), pm1)
+		e2 := schnorrChallenge(sig2.r, []byte(fmt.Sprintf("second %d", i)), pm1)
+
+		// e1 - e2 has to be invertible mod p-1, otherwise try another pair of messages
+		de := new(big.Int).Sub(e1, e2)
+		deInv := new(big.Int).ModInverse(de.Mod(de, pm1), pm1)
+		if deInv == nil {
+			continue
+		}
+
+		a := new(big.Int).Sub(sig1.s, sig2.s)
+		a.Mul(a, deInv)
+		a.Mod(a, pm1)
+
+		if new(big.Int).Exp(public.g, a, public.p).Cmp(public.h) != 0 {
+			t.Errorf("Recovered key does not match the public key")
+		}
+		return
+	}
+
+	t.Errorf("Never found an invertible e1 - e2")
+}
+
+// RFC 6979 appendix A.2.1, DSA 1024 bits, SHA-256, message "sample"
+func TestNonceGeneratorRFC6979(t *testing.T) {
+	q, _ := new(big.Int).SetString("996F967F6C8E388D9E28D01E205FBA957A5698B1", 16)
+	x, _ := new(big.Int).SetString("411602CB19A6CCC34494D79D98EF1E7ED5AF25F7", 16)
+	expected, _ := new(big.Int).SetString("519BA0546D0C39202A7D34D7DFA5E760B318BCFB", 16)
+
+	digest := sha256.Sum256([]byte("sample"))
+	k := newNonceGenerator(sha256.New, q, x, digest[:]).next()
+
+	if k.Cmp(expected) != 0 {
+		t.Errorf("k = %X, expected %X", k, expected)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// This is a streaming version of ElGamal Encrypt and Decrypt
+// Blocks are encrypted (or decrypted) on a pool of workers and written back out in the order they were read
+// On the wire every block is the shared secret and the ciphertext, both padded to the byte length of p,
+// followed by 4 bytes holding the number of plaintext bytes in the block
+
+// The number of bytes in a single encoded ElGamalCipherText
+func (pk *ElGamalPublicKey) encodedSize() int {
+	return 2*pk.byteLen() + 4
+}
+
+// The number of bytes needed to hold any number mod p
+func (pk *ElGamalPublicKey) byteLen() int {
+	return (pk.p.BitLen() + 7) / 8
+}
+
+// Encodes a ciphertext as shared || ciphertext || size
+func (pk *ElGamalPublicKey) encodeCipherText(c *ElGamalCipherText) []byte {
+	k := pk.byteLen()
+	b := make([]byte, pk.encodedSize())
+
+	c.shared.FillBytes(b[:k])
+	c.ciphertext.FillBytes(b[k : 2*k])
+
+	b[2*k] = byte(c.size >> 24)
+	b[2*k+1] = byte(c.size >> 16)
+	b[2*k+2] = byte(c.size >> 8)
+	b[2*k+3] = byte(c.size)
+
+	return b
+}
+
+// Decodes a ciphertext produced by encodeCipherText
+func (pk *ElGamalPublicKey) decodeCipherText(b []byte) (*ElGamalCipherText, error) {
+	if len(b) != pk.encodedSize() {
+		return nil, fmt.Errorf("encoded ciphertext must be %d bytes", pk.encodedSize())
+	}
+
+	k := pk.byteLen()
+	shared := new(big.Int).SetBytes(b[:k])
+	ciphertext := new(big.Int).SetBytes(b[k : 2*k])
+	size := int(b[2*k])<<24 | int(b[2*k+1])<<16 | int(b[2*k+2])<<8 | int(b[2*k+3])
+
+	// EncryptStream never writes a block longer than the block size
+	if size > pk.blockSize() {
+		return nil, fmt.Errorf("block size %d is out of range, blocks hold at most %d bytes", size, pk.blockSize())
+	}
+
+	return &ElGamalCipherText{shared, ciphertext, size}, nil
+}
+
+// EncryptStream reads plaintext from r until EOF and writes the encrypted blocks to w.
+// Blocks are encrypted on workers goroutines (runtime.NumCPU() when workers < 1).
+// Stops early and returns the context's error if ctx is cancelled.
+func (pk *ElGamalPublicKey) EncryptStream(ctx context.Context, r io.Reader, w io.Writer, workers int) error {
+	// same block size as Encrypt
+	bs := pk.blockSize()
+
+	next := func() ([]byte, error) {
+		block := make([]byte, bs)
+		n, err := io.ReadFull(r, block)
+		if err == io.ErrUnexpectedEOF {
+			// the last block is allowed to be short
+			return block[:n], nil
+		}
+
+		return block, err
+	}
+
+	encrypt := func(block []byte) ([]byte, error) {
+		c, err := pk._encrypt(new(big.Int).SetBytes(block))
+		if err != nil {
+			return nil, err
+		}
+
+		c.size = len(block)
+		return pk.encodeCipherText(&c), nil
+	}
+
+	return processStream(ctx, workers, next, encrypt, w)
+}
+
+// DecryptStream reads blocks written by EncryptStream from r until EOF and writes the plaintext to w.
+// Blocks are decrypted on workers goroutines (runtime.NumCPU() when workers < 1).
+// Stops early and returns the context's error if ctx is cancelled.
+func (sk *ElGamalPrivateKey) DecryptStream(ctx context.Context, r io.Reader, w io.Writer, workers int) error {
+	next := func() ([]byte, error) {
+		block := make([]byte, sk.public.encodedSize())
+		_, err := io.ReadFull(r, block)
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated ciphertext block")
+		}
+
+		return block, err
+	}
+
+	decrypt := func(block []byte) ([]byte, error) {
+		c, err := sk.public.decodeCipherText(block)
+		if err != nil {
+			return nil, err
+		}
+
+		return sk.Decrypt([]*ElGamalCipherText{c})
+	}
+
+	return processStream(ctx, workers, next, decrypt, w)
+}
+
+// The result of transforming a single block
+type streamResult struct {
+	data []byte
+	err  error
+}
+
+// A block waiting for a worker, along with where to put the result
+type streamJob struct {
+	block []byte
+	out   chan streamResult
+}
+
+// processStream calls next until it returns io.EOF, transforms every block with fn on a pool of workers
+// and writes the results to w in the same order that next returned them.
+// At most workers blocks are waiting to be written at any time.
+func processStream(ctx context.Context, workers int, next func() ([]byte, error), fn func([]byte) ([]byte, error), w io.Writer) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start the workers
+	jobs := make(chan streamJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				data, err := fn(job.block)
+				job.out <- streamResult{data, err}
+			}
+		}()
+	}
+
+	// The writer waits on results in the order the blocks were read
+	pending := make(chan chan streamResult, workers)
+	written := make(chan error, 1)
+	go func() {
+		for out := range pending {
+			var result streamResult
+			select {
+			case result = <-out:
+			case <-ctx.Done():
+				written <- ctx.Err()
+				return
+			}
+
+			if result.err == nil {
+				_, result.err = w.Write(result.data)
+			}
+
+			if result.err != nil {
+				// Stop the reader
+				cancel()
+				written <- result.err
+				return
+			}
+		}
+
+		written <- nil
+	}()
+
+	// Read blocks and hand them out to the workers
+	var readErr error
+ReadLoop:
+	for {
+		if readErr = ctx.Err(); readErr != nil {
+			break
+		}
+
+		block, err := next()
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+
+		job := streamJob{block, make(chan streamResult, 1)}
+
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			readErr = ctx.Err()
+			break ReadLoop
+		}
+
+		select {
+		case pending <- job.out:
+		case <-ctx.Done():
+			readErr = ctx.Err()
+			break ReadLoop
+		}
+	}
+
+	close(jobs)
+	close(pending)
+
+	writeErr := <-written
+	wg.Wait()
+
+	// A failure in the writer cancels the context, so it is the more interesting error
+	if writeErr != nil {
+		return writeErr
+	}
+
+	return readErr
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Round trips messages of different sizes through the streaming API with different numbers of workers
+func TestElGamalStream(t *testing.T) {
+	var sizes = []int{0, 1, 31, 32, 100, 1000, 10000}
+	message := make([]byte, 10000)
+	rand.Read(message)
+
+	private, public := Keygen(256)
+
+	for _, workers := range []int{0, 1, 4} {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("Workers: %d Size: %d", workers, size), func(t *testing.T) {
+				ciphertext := bytes.Buffer{}
+				err := public.EncryptStream(context.Background(), bytes.NewReader(message[:size]), &ciphertext, workers)
+				if err != nil {
+					t.Fatalf("Could not encrypt message: %s", err)
+				}
+
+				if ciphertext.Len()%public.encodedSize() != 0 {
+					t.Errorf("Ciphertext length %d is not a multiple of the block size", ciphertext.Len())
+				}
+
+				plaintext := bytes.Buffer{}
+				err = private.DecryptStream(context.Background(), &ciphertext, &plaintext, workers)
+				if err != nil {
+					t.Fatalf("Could not decrypt message: %s", err)
+				}
+
+				if !bytes.Equal(plaintext.Bytes(), message[:size]) {
+					t.Errorf("Decrypted message does not match original message")
+				}
+			})
+		}
+	}
+}
+
+// The streaming format holds the same ciphertexts that Encrypt returns
+func TestElGamalStreamMatchesEncrypt(t *testing.T) {
+	message := make([]byte, 1000)
+	rand.Read(message)
+
+	private, public := Keygen(256)
+
+	ciphers, err := public.Encrypt(message)
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+
+	encoded := bytes.Buffer{}
+	for _, c := range ciphers {
+		encoded.Write(public.encodeCipherText(c))
+	}
+
+	plaintext := bytes.Buffer{}
+	err = private.DecryptStream(context.Background(), &encoded, &plaintext, 4)
+	if err != nil {
+		t.Fatalf("Could not decrypt message: %s", err)
+	}
+
+	if !bytes.Equal(plaintext.Bytes(), message) {
+		t.Errorf("Decrypted message does not match original message")
+	}
+}
+
+func TestElGamalStreamTruncated(t *testing.T) {
+	private, public := Keygen(256)
+
+	ciphertext := bytes.Buffer{}
+	err := public.EncryptStream(context.Background(), bytes.NewReader([]byte("Hello World!")), &ciphertext, 1)
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+
+	truncated := bytes.NewReader(ciphertext.Bytes()[:ciphertext.Len()-1])
+	err = private.DecryptStream(context.Background(), truncated, &bytes.Buffer{}, 1)
+	if err == nil {
+		t.Errorf("Expected an error decrypting a truncated ciphertext")
+	}
+}
+
+// The length field is untrusted, a forged one must be rejected instead of padding the block with zeros
+func TestElGamalStreamForgedLength(t *testing.T) {
+	private, public := Keygen(256)
+
+	ciphertext := bytes.Buffer{}
+	err := public.EncryptStream(context.Background(), bytes.NewReader([]byte("Hello World!")), &ciphertext, 1)
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+
+	for _, size := range []uint32{uint32(public.blockSize()) + 1, 1 << 28, 0xffffffff, 1} {
+		t.Run(fmt.Sprintf("Size: %d", size), func(t *testing.T) {
+			forged := append([]byte{}, ciphertext.Bytes()...)
+			n := len(forged)
+			forged[n-4], forged[n-3], forged[n-2], forged[n-1] = byte(size>>24), byte(size>>16), byte(size>>8), byte(size)
+
+			plaintext := bytes.Buffer{}
+			err := private.DecryptStream(context.Background(), bytes.NewReader(forged), &plaintext, 1)
+			if err == nil {
+				t.Errorf("Expected an error decrypting a block with a forged length")
+			}
+
+			if plaintext.Len() != 0 {
+				t.Errorf("Wrote %d bytes of a block with a forged length", plaintext.Len())
+			}
+		})
+	}
+}
+
+func TestElGamalStreamCancel(t *testing.T) {
+	_, public := Keygen(256)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := public.EncryptStream(ctx, bytes.NewReader(make([]byte, 10000)), &bytes.Buffer{}, 4)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// A writer that fails after n writes
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestElGamalStreamWriteError(t *testing.T) {
+	_, public := Keygen(256)
+
+	err := public.EncryptStream(context.Background(), bytes.NewReader(make([]byte, 10000)), &failingWriter{3}, 4)
+	if err == nil || err.Error() != "write failed" {
+		t.Errorf("Expected the writer's error, got %v", err)
+	}
+}
+
+func benchmarkMessage(b *testing.B) (*ElGamalPrivateKey, *ElGamalPublicKey, []byte) {
+	private, public := Keygen(1024)
+	message := make([]byte, 16*1024)
+	rand.Read(message)
+
+	b.SetBytes(int64(len(message)))
+	b.ResetTimer()
+	return private, public, message
+}
+
+func BenchmarkElGamalEncryptSerial(b *testing.B) {
+	_, public, message := benchmarkMessage(b)
+
+	for i := 0; i < b.N; i++ {
+		if _, err := public.Encrypt(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkElGamalEncryptStream(b *testing.B) {
+	_, public, message := benchmarkMessage(b)
+
+	for i := 0; i < b.N; i++ {
+		err := public.EncryptStream(context.Background(), bytes.NewReader(message), &bytes.Buffer{}, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkElGamalDecryptSerial(b *testing.B) {
+	private, public, message := benchmarkMessage(b)
+
+	ciphers, err := public.Encrypt(message)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := private.Decrypt(ciphers); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkElGamalDecryptStream(b *testing.B) {
+	private, public, message := benchmarkMessage(b)
+
+	ciphertext := bytes.Buffer{}
+	err := public.EncryptStream(context.Background(), bytes.NewReader(message), &ciphertext, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := private.DecryptStream(context.Background(), bytes.NewReader(ciphertext.Bytes()), &bytes.Buffer{}, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
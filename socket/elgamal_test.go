@@ -62,3 +62,33 @@ func TestElGamalParallel(t *testing.T) {
 		})
 	}
 }
+
+// A product from Mul fills the whole of p, one byte more than Encrypt's blocks, and still decrypts
+func TestElGamalDecryptProduct(t *testing.T) {
+	private, public := Keygen(256)
+
+	ciphers, err := public.Encrypt([]byte("ab"))
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+
+	product, err := public.Mul(ciphers[0], ciphers[0])
+	if err != nil {
+		t.Fatalf("Could not multiply ciphertexts: %s", err)
+	}
+
+	plaintext, err := private.Decrypt([]*ElGamalCipherText{product})
+	if err != nil {
+		t.Fatalf("Could not decrypt product: %s", err)
+	}
+
+	if len(plaintext) != public.byteLen() {
+		t.Errorf("Product decrypted to %d bytes, expected %d", len(plaintext), public.byteLen())
+	}
+
+	// Anything longer than p is still rejected
+	product.size = public.byteLen() + 1
+	if _, err := private.Decrypt([]*ElGamalCipherText{product}); err == nil {
+		t.Errorf("Expected an error decrypting a block longer than p")
+	}
+}
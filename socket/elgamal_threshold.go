@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/Alextopher/crypto/numtheory"
+)
+
+// Threshold ElGamal splits the private exponent a into n Shamir shares so that any t of them can decrypt.
+// No single share holder learns a: each one publishes a partial decryption shared^a_i and the partials
+// are combined in the exponent with Lagrange coefficients, shared^a = prod (shared^a_i)^lambda_i.
+//
+// Lagrange interpolation needs to divide exponents, so the group must have prime order.
+// KeygenThreshold uses a safe prime p = 2q + 1 and a generator of the subgroup of order q.
+
+// The public half of a threshold key
+type ElGamalThresholdKey struct {
+	// The ElGamal public key that messages are encrypted with
+	public *ElGamalPublicKey
+
+	// The prime order of g
+	q *big.Int
+
+	// The number of shares needed to decrypt
+	threshold int
+
+	// verification[i-1] = g^a_i mod p, used to check the partial decryption of share i
+	verification []*big.Int
+}
+
+// A single share of a threshold private key
+type ElGamalKeyShare struct {
+	// The x coordinate of the share, starting at 1
+	index int
+
+	// a_i = f(index) mod q
+	a *big.Int
+
+	key *ElGamalThresholdKey
+}
+
+// One share holder's contribution towards decrypting a ciphertext
+type PartialDecryption struct {
+	// The index of the share that made this
+	index int
+
+	// shared^a_i mod p
+	d *big.Int
+
+	// Optional proof that d was computed with the share's exponent
+	proof *ChaumPedersenProof
+}
+
+// A non-interactive Chaum-Pedersen proof that log_g(h_i) = log_shared(d)
+type ChaumPedersenProof struct {
+	// Commitments g^w and shared^w
+	a, b *big.Int
+
+	// z = w + e*a_i mod q
+	z *big.Int
+}
+
+// KeygenThreshold generates a keysize bit safe prime group and a private key split into n shares,
+// any threshold of which can decrypt.
+func KeygenThreshold(keysize, threshold, n int) (*ElGamalThresholdKey, []*ElGamalKeyShare, error) {
+	if threshold < 1 || threshold > n {
+		return nil, nil, fmt.Errorf("threshold must be between 1 and %d", n)
+	}
+
+	p, q, err := numtheory.SafePrime(rand.Reader, keysize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Squaring a random element lands in the subgroup of quadratic residues, which has order q
+	g := big.NewInt(1)
+	for g.Cmp(big.NewInt(1)) == 0 {
+		x, err := rand.Int(rand.Reader, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		g.Exp(x, big.NewInt(2), p)
+	}
+
+	// f(x) = a + c_1 x + ... + c_(t-1) x^(t-1) mod q
+	coefficients := make([]*big.Int, threshold)
+	for i := range coefficients {
+		coefficients[i], err = rand.Int(rand.Reader, q)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	key := &ElGamalThresholdKey{
+		public: &ElGamalPublicKey{
+			p: p,
+			g: g,
+			h: new(big.Int).Exp(g, coefficients[0], p),
+		},
+		q:            q,
+		threshold:    threshold,
+		verification: make([]*big.Int, n),
+	}
+
+	shares := make([]*ElGamalKeyShare, n)
+	for i := range shares {
+		x := big.NewInt(int64(i + 1))
+
+		// Horner's method
+		a := new(big.Int)
+		for j := threshold - 1; j >= 0; j-- {
+			a.Mul(a, x)
+			a.Add(a, coefficients[j])
+			a.Mod(a, q)
+		}
+
+		shares[i] = &ElGamalKeyShare{i + 1, a, key}
+		key.verification[i] = new(big.Int).Exp(g, a, p)
+	}
+
+	return key, shares, nil
+}
+
+// Public returns the key that messages for the share holders are encrypted with
+func (key *ElGamalThresholdKey) Public() *ElGamalPublicKey {
+	return key.public
+}
+
+// PartialDecrypt computes this share's contribution towards decrypting c
+func (share *ElGamalKeyShare) PartialDecrypt(c *ElGamalCipherText) (*PartialDecryption, error) {
+	if err := share.key.checkShared(c); err != nil {
+		return nil, err
+	}
+
+	d := new(big.Int).Exp(c.shared, share.a, share.key.public.p)
+	return &PartialDecryption{index: share.index, d: d}, nil
+}
+
+// PartialDecryptWithProof is PartialDecrypt along with a proof that the partial decryption is correct
+func (share *ElGamalKeyShare) PartialDecryptWithProof(c *ElGamalCipherText) (*PartialDecryption, error) {
+	partial, err := share.PartialDecrypt(c)
+	if err != nil {
+		return nil, err
+	}
+
+	key := share.key
+	p := key.public.p
+
+	// Random commitment
+	w, err := rand.Int(rand.Reader, key.q)
+	if err != nil {
+		return nil, err
+	}
+
+	a := new(big.Int).Exp(key.public.g, w, p)
+	b := new(big.Int).Exp(c.shared, w, p)
+	e := key.challenge(key.verification[share.index-1], c.shared, partial.d, a, b)
+
+	// z = w + e*a_i mod q
+	z := new(big.Int).Mul(e, share.a)
+	z.Add(z, w)
+	z.Mod(z, key.q)
+
+	partial.proof = &ChaumPedersenProof{a, b, z}
+	return partial, nil
+}
+
+// VerifyPartial checks the proof attached to a partial decryption of c
+func (key *ElGamalThresholdKey) VerifyPartial(c *ElGamalCipherText, partial *PartialDecryption) bool {
+	if partial == nil || partial.proof == nil || partial.d == nil {
+		return false
+	}
+
+	if partial.index < 1 || partial.index > len(key.verification) {
+		return false
+	}
+
+	if key.checkShared(c) != nil {
+		return false
+	}
+
+	p := key.public.p
+	hi := key.verification[partial.index-1]
+	proof := partial.proof
+	e := key.challenge(hi, c.shared, partial.d, proof.a, proof.b)
+
+	// g^z = a * h_i^e
+	left := new(big.Int).Exp(key.public.g, proof.z, p)
+	right := new(big.Int).Exp(hi, e, p)
+	right.Mul(right, proof.a)
+	right.Mod(right, p)
+	if left.Cmp(right) != 0 {
+		return false
+	}
+
+	// shared^z = b * d^e
+	left.Exp(c.shared, proof.z, p)
+	right.Exp(partial.d, e, p)
+	right.Mul(right, proof.b)
+	right.Mod(right, p)
+
+	return left.Cmp(right) == 0
+}
+
+// The Fiat-Shamir challenge for a Chaum-Pedersen proof
+func (key *ElGamalThresholdKey) challenge(values ...*big.Int) *big.Int {
+	h := sha256.New()
+	for _, v := range append([]*big.Int{key.public.p, key.public.g}, values...) {
+		b := v.Bytes()
+		h.Write([]byte{byte(len(b) >> 24), byte(len(b) >> 16), byte(len(b) >> 8), byte(len(b))})
+		h.Write(b)
+	}
+
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, key.q)
+}
+
+// The shared secret must be in the subgroup of order q for the proofs to mean anything
+func (key *ElGamalThresholdKey) checkShared(c *ElGamalCipherText) error {
+	if err := key.public.checkCipherText(c); err != nil {
+		return err
+	}
+
+	if new(big.Int).Exp(c.shared, key.q, key.public.p).Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("shared secret is not in the subgroup")
+	}
+
+	return nil
+}
+
+// Combine decrypts c from at least threshold partial decryptions.
+// Partial decryptions that carry a proof are checked and rejected if the proof is invalid.
+func (key *ElGamalThresholdKey) Combine(c *ElGamalCipherText, partials []*PartialDecryption) ([]byte, error) {
+	if err := key.checkShared(c); err != nil {
+		return nil, err
+	}
+
+	if len(partials) < key.threshold {
+		return nil, fmt.Errorf("need %d partial decryptions, got %d", key.threshold, len(partials))
+	}
+
+	seen := make(map[int]bool)
+	for _, partial := range partials {
+		if partial == nil || partial.d == nil {
+			return nil, fmt.Errorf("incomplete partial decryption")
+		}
+
+		if partial.index < 1 || partial.index > len(key.verification) {
+			return nil, fmt.Errorf("unknown share %d", partial.index)
+		}
+
+		if seen[partial.index] {
+			return nil, fmt.Errorf("duplicate partial decryption from share %d", partial.index)
+		}
+		seen[partial.index] = true
+
+		if partial.proof != nil && !key.VerifyPartial(c, partial) {
+			return nil, fmt.Errorf("invalid proof from share %d", partial.index)
+		}
+	}
+
+	// fullmask = prod d_i^lambda_i where lambda_i = prod_(j != i) x_j / (x_j - x_i) mod q
+	p := key.public.p
+	fullmask := big.NewInt(1)
+	for _, partial := range partials {
+		num := big.NewInt(1)
+		den := big.NewInt(1)
+		for _, other := range partials {
+			if other.index == partial.index {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(other.index)))
+			den.Mul(den, big.NewInt(int64(other.index-partial.index)))
+		}
+
+		lambda, err := numtheory.ModInverse(den, key.q)
+		if err != nil {
+			return nil, fmt.Errorf("share indices must be distinct mod q: %w", err)
+		}
+		lambda.Mul(lambda, num)
+		lambda.Mod(lambda, key.q)
+
+		fullmask.Mul(fullmask, new(big.Int).Exp(partial.d, lambda, p))
+		fullmask.Mod(fullmask, p)
+	}
+
+	// m = ciphertext * fullmask^-1
+	plaintext, err := numtheory.ModInverse(fullmask, p)
+	if err != nil {
+		return nil, fmt.Errorf("partial decryptions don't combine to an invertible mask: %w", err)
+	}
+	plaintext.Mul(plaintext, c.ciphertext)
+	plaintext.Mod(plaintext, p)
+
+	return key.public.blockBytes(plaintext, c.size)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Decrypts ciphers with the partial decryptions of the shares in subset
+func thresholdDecrypt(key *ElGamalThresholdKey, shares []*ElGamalKeyShare, subset []int, ciphers []*ElGamalCipherText) ([]byte, error) {
+	plaintext := make([]byte, 0)
+	for _, c := range ciphers {
+		partials := make([]*PartialDecryption, 0, len(subset))
+		for _, i := range subset {
+			partial, err := shares[i].PartialDecryptWithProof(c)
+			if err != nil {
+				return nil, err
+			}
+			partials = append(partials, partial)
+		}
+
+		b, err := key.Combine(c, partials)
+		if err != nil {
+			return nil, err
+		}
+		plaintext = append(plaintext, b...)
+	}
+
+	return plaintext, nil
+}
+
+// Every subset of the shares as a list of indices
+func subsets(n int) [][]int {
+	all := make([][]int, 0, 1<<n)
+	for mask := 0; mask < 1<<n; mask++ {
+		subset := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, i)
+			}
+		}
+		all = append(all, subset)
+	}
+	return all
+}
+
+func TestThresholdDecrypt(t *testing.T) {
+	message := []byte("Escrowed across several operators")
+
+	for _, params := range [][2]int{{1, 1}, {1, 3}, {2, 3}, {3, 5}, {5, 5}} {
+		threshold, n := params[0], params[1]
+
+		t.Run(fmt.Sprintf("%d of %d", threshold, n), func(t *testing.T) {
+			key, shares, err := KeygenThreshold(128, threshold, n)
+			if err != nil {
+				t.Fatalf("Could not generate key: %s", err)
+			}
+
+			ciphers, err := key.Public().Encrypt(message)
+			if err != nil {
+				t.Fatalf("Could not encrypt message: %s", err)
+			}
+
+			for _, subset := range subsets(n) {
+				plaintext, err := thresholdDecrypt(key, shares, subset, ciphers)
+
+				if len(subset) < threshold {
+					if err == nil && string(plaintext) == string(message) {
+						t.Errorf("Shares %v decrypted the message below the threshold", subset)
+					}
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("Shares %v could not decrypt: %s", subset, err)
+					continue
+				}
+
+				if string(plaintext) != string(message) {
+					t.Errorf("Shares %v decrypted to %q", subset, plaintext)
+				}
+			}
+		})
+	}
+}
+
+// Share holders can decrypt a product from Mul, which fills the whole of p
+func TestThresholdDecryptProduct(t *testing.T) {
+	key, shares, err := KeygenThreshold(128, 2, 3)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	ciphers, err := key.Public().Encrypt([]byte("ab"))
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+
+	product, err := key.Public().Mul(ciphers[0], ciphers[0])
+	if err != nil {
+		t.Fatalf("Could not multiply ciphertexts: %s", err)
+	}
+
+	plaintext, err := thresholdDecrypt(key, shares, []int{0, 2}, []*ElGamalCipherText{product})
+	if err != nil {
+		t.Fatalf("Could not decrypt product: %s", err)
+	}
+
+	expected := new(big.Int).SetBytes([]byte("ab"))
+	expected.Mul(expected, expected)
+	expected.Mod(expected, key.public.p)
+	if new(big.Int).SetBytes(plaintext).Cmp(expected) != 0 || len(plaintext) != key.public.byteLen() {
+		t.Errorf("Product decrypted to %x, expected %x", plaintext, expected)
+	}
+}
+
+func TestThresholdKeyIsConsistent(t *testing.T) {
+	key, shares, err := KeygenThreshold(128, 3, 5)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	p := key.public.p
+
+	// p = 2q + 1 and g has order q
+	if new(big.Int).Add(new(big.Int).Lsh(key.q, 1), big.NewInt(1)).Cmp(p) != 0 {
+		t.Errorf("p is not 2q + 1")
+	}
+
+	if new(big.Int).Exp(key.public.g, key.q, p).Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("g does not have order q")
+	}
+
+	// Interpolating the shares at 0 gives back a
+	a := new(big.Int)
+	for _, i := range shares[:3] {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for _, j := range shares[:3] {
+			if i.index != j.index {
+				num.Mul(num, big.NewInt(int64(j.index)))
+				den.Mul(den, big.NewInt(int64(j.index-i.index)))
+			}
+		}
+		lambda := new(big.Int).ModInverse(den.Mod(den, key.q), key.q)
+		lambda.Mul(lambda, num)
+		a.Add(a, lambda.Mul(lambda, i.a))
+	}
+	a.Mod(a, key.q)
+
+	if new(big.Int).Exp(key.public.g, a, p).Cmp(key.public.h) != 0 {
+		t.Errorf("Shares do not interpolate to the private key")
+	}
+}
+
+func TestThresholdRejectsBadPartials(t *testing.T) {
+	key, shares, err := KeygenThreshold(128, 2, 3)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+
+	ciphers, err := key.Public().Encrypt([]byte("hi"))
+	if err != nil {
+		t.Fatalf("Could not encrypt message: %s", err)
+	}
+	c := ciphers[0]
+
+	good, _ := shares[0].PartialDecryptWithProof(c)
+	other, _ := shares[1].PartialDecryptWithProof(c)
+
+	if !key.VerifyPartial(c, good) {
+		t.Errorf("Valid proof did not verify")
+	}
+
+	// A share holder lying about its partial decryption is caught by the proof
+	bad := &PartialDecryption{good.index, new(big.Int).Mul(good.d, key.public.g), good.proof}
+	bad.d.Mod(bad.d, key.public.p)
+	if key.VerifyPartial(c, bad) {
+		t.Errorf("Proof verified for a wrong partial decryption")
+	}
+
+	if _, err := key.Combine(c, []*PartialDecryption{bad, other}); err == nil {
+		t.Errorf("Combine accepted a partial decryption with an invalid proof")
+	}
+
+	// A proof made for a different share doesn't transfer
+	stolen := &PartialDecryption{good.index, other.d, other.proof}
+	if key.VerifyPartial(c, stolen) {
+		t.Errorf("Proof verified for the wrong share")
+	}
+
+	if _, err := key.Combine(c, []*PartialDecryption{good, good}); err == nil {
+		t.Errorf("Combine accepted duplicate partial decryptions")
+	}
+
+	if _, err := key.Combine(c, []*PartialDecryption{good}); err == nil {
+		t.Errorf("Combine accepted fewer partial decryptions than the threshold")
+	}
+
+	// The block length comes with the ciphertext and is bounded by the byte length of p
+	forged := &ElGamalCipherText{c.shared, c.ciphertext, 1 << 30}
+	if _, err := key.Combine(forged, []*PartialDecryption{good, other}); err == nil {
+		t.Errorf("Combine accepted a ciphertext with a forged length")
+	}
+}
+
+func TestKeygenThresholdArguments(t *testing.T) {
+	for _, params := range [][2]int{{0, 3}, {4, 3}, {-1, 1}} {
+		if _, _, err := KeygenThreshold(64, params[0], params[1]); err == nil {
+			t.Errorf("Expected an error for %d of %d", params[0], params[1])
+		}
+	}
+}
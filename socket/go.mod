@@ -1,3 +1,18 @@
 module github.com/Alextopher/cyrpto/socket
 
 go 1.18
+
+require (
+	github.com/Alextopher/crypto/aes v0.0.0
+	github.com/Alextopher/crypto/fingerprint v0.0.0
+	github.com/Alextopher/crypto/keyfile v0.0.0
+	github.com/Alextopher/crypto/numtheory v0.0.0
+)
+
+replace github.com/Alextopher/crypto/aes => ../aes
+
+replace github.com/Alextopher/crypto/fingerprint => ../fingerprint
+
+replace github.com/Alextopher/crypto/keyfile => ../keyfile
+
+replace github.com/Alextopher/crypto/numtheory => ../numtheory
@@ -2,17 +2,32 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"net"
 	"os"
+
+	"github.com/Alextopher/crypto/keyfile"
 )
 
 func main() {
 	// use a command line flag to choose if we are the server or client
 	server := flag.Bool("server", false, "Run as a server")
+	keyFile := flag.String("key", "", "Load our ElGamal key from this file, or save a new one to it")
 	flag.Parse()
 
+	// Without a key file every chat uses a new key
+	var private *ElGamalPrivateKey
+	if *keyFile != "" {
+		var err error
+		private, err = loadOrCreateKey(*keyFile)
+		if err != nil {
+			fmt.Println("Error with key:", err.Error())
+			os.Exit(1)
+		}
+	}
+
 	// the argument is our name, the second argument is the host:port to connect to
 	// if we are the server you can just use localhost:port
 	var conn net.Conn
@@ -49,7 +64,19 @@ func main() {
 		defer conn.Close()
 	}
 
-	s := NewSocket(conn)
+	var s *Socket
+	if private != nil {
+		s = NewSocketWithKey(conn, private)
+	} else {
+		s = NewSocket(conn)
+	}
+
+	// Show both keys so the fingerprints can be compared over another channel, a man in the middle would have a different key
+	fmt.Println("Our key:")
+	s.private.public.Inspect(os.Stdout)
+	fmt.Println("Their key:")
+	s.public.Inspect(os.Stdout)
+	fmt.Println("Check their fingerprint with them over another channel before trusting the chat")
 
 	// Create a thread to handle sending messages
 	go func() {
@@ -65,3 +92,46 @@ func main() {
 		fmt.Println(string(msg))
 	}
 }
+
+// The passphrase of the key file is read from this environment variable when it is set, otherwise from the terminal
+const passphraseEnv = "SOCKET_PASSPHRASE"
+
+// Reads our ElGamal key, or makes a new one and saves it encrypted under a passphrase if the file doesn't exist
+func loadOrCreateKey(name string) (*ElGamalPrivateKey, error) {
+	data, err := os.ReadFile(name)
+	if err == nil {
+		var passphrase []byte
+		if keyfile.IsEncrypted(data) {
+			passphrase, err = keyfile.ReadPassphrase(passphraseEnv, "Enter passphrase for "+name+": ")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return ParseElGamalPrivateKey(data, passphrase)
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	passphrase, err := keyfile.NewPassphrase(passphraseEnv, "Enter a passphrase for the new key "+name+" (empty for no passphrase): ")
+	if err != nil {
+		return nil, err
+	}
+
+	// force the use of 512 bit prime
+	private, _ := Keygen(512)
+
+	var buf bytes.Buffer
+	if err := private.SavePEM(&buf, passphrase); err != nil {
+		return nil, err
+	}
+
+	if err := keyfile.WriteFile(name, buf.Bytes(), 0600); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Saved a new key to", name)
+	return private, nil
+}
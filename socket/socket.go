@@ -5,6 +5,8 @@ import (
 	"io"
 	"math/big"
 	"net"
+
+	"github.com/Alextopher/crypto/aes"
 )
 
 // This is the code that handles key exchange and aes symmetric encryption
@@ -26,24 +28,30 @@ type Socket struct {
 	public *ElGamalPublicKey
 
 	// There are seperate aes ciphers for sending messages and recieving messages
-	aesSend *AES
-	aesRecv *AES
+	aesSend *aes.AES
+	aesRecv *aes.AES
 
 	// A flag that indicates if the handshake has been completed
 	handshook bool
 }
 
 func NewSocket(conn net.Conn) *Socket {
+	// force the use of 512 bit prime
+	private, _ := Keygen(512)
+
+	return NewSocketWithKey(conn, private)
+}
+
+// Like NewSocket but with a saved ElGamal key, so the other client sees the same fingerprint every time
+func NewSocketWithKey(conn net.Conn, private *ElGamalPrivateKey) *Socket {
 	// Create a new socket
 	s := &Socket{
-		conn: conn,
-		send: make(chan []byte),
-		recv: make(chan []byte),
+		conn:    conn,
+		send:    make(chan []byte),
+		recv:    make(chan []byte),
+		private: private,
 	}
 
-	// force the use of 512 bit prime
-	s.private, _ = Keygen(512)
-
 	// Start the send and recieve goroutines
 	go s.sendLoop()
 	go s.recvLoop()
@@ -67,7 +75,7 @@ func (s *Socket) Handshake() {
 	h := new(big.Int).SetBytes(<-s.recv)
 
 	// Create the other client's ElGamal public key
-	s.public = &ElGamalPublicKey{p, g, h}
+	s.public = &ElGamalPublicKey{p: p, g: g, h: h}
 
 	// Choose a random 32 bytes (16 bytes per key) to act as our half of the shared secret
 	ourSecret := make([]byte, 32)
@@ -101,6 +109,15 @@ func (s *Socket) Handshake() {
 		s.send <- sizeBytes
 	}
 
+	// Sign the ciphers so the other client knows they came from the owner of our public key
+	signature, err := s.private.SignSchnorr(handshakeTranscript(s.public, ciphers))
+	if err != nil {
+		panic("could not sign ourSecret")
+	}
+
+	s.send <- signature.r.Bytes()
+	s.send <- signature.s.Bytes()
+
 	// Read the number of ciphers we are receiving
 	numCiphers := int((<-s.recv)[0])
 
@@ -119,6 +136,16 @@ func (s *Socket) Handshake() {
 		receivedCiphers[i] = &ElGamalCipherText{shared, ciphertext, size}
 	}
 
+	// Check the other client's signature over the ciphers
+	friendSignature := &SchnorrSignature{
+		r: new(big.Int).SetBytes(<-s.recv),
+		s: new(big.Int).SetBytes(<-s.recv),
+	}
+
+	if !s.public.VerifySchnorr(handshakeTranscript(s.private.public, receivedCiphers), friendSignature) {
+		panic("invalid handshake signature")
+	}
+
 	// Decrypt our friend's secret with our ElGamal private key
 	friendSecret, err := s.private.Decrypt(receivedCiphers)
 	if err != nil {
@@ -136,22 +163,22 @@ func (s *Socket) Handshake() {
 	friendInt := new(big.Int).SetBytes(friendSecret)
 
 	if ourInt.Cmp(friendInt) > 0 {
-		s.aesSend, err = NewAES(sharedSecret[:16])
+		s.aesSend, err = aes.NewAES(sharedSecret[:16])
 		if err != nil {
 			panic("could not create aesSend")
 		}
 
-		s.aesRecv, err = NewAES(sharedSecret[16:])
+		s.aesRecv, err = aes.NewAES(sharedSecret[16:])
 		if err != nil {
 			panic("could not create aesRecv")
 		}
 	} else if ourInt.Cmp(friendInt) < 0 {
-		s.aesSend, err = NewAES(sharedSecret[16:])
+		s.aesSend, err = aes.NewAES(sharedSecret[16:])
 		if err != nil {
 			panic("could not create aesSend")
 		}
 
-		s.aesRecv, err = NewAES(sharedSecret[:16])
+		s.aesRecv, err = aes.NewAES(sharedSecret[:16])
 		if err != nil {
 			panic("could not create aesRecv")
 		}
@@ -163,6 +190,19 @@ func (s *Socket) Handshake() {
 	s.handshook = true
 }
 
+// The bytes signed during the handshake: the public key the ciphers were encrypted for followed by the ciphers
+func handshakeTranscript(public *ElGamalPublicKey, ciphers []*ElGamalCipherText) []byte {
+	transcript := public.marshal()
+
+	for _, cipher := range ciphers {
+		transcript = appendInt(transcript, cipher.shared)
+		transcript = appendInt(transcript, cipher.ciphertext)
+		transcript = appendInt(transcript, big.NewInt(int64(cipher.size)))
+	}
+
+	return transcript
+}
+
 func (s *Socket) sendLoop() {
 	for {
 		// Get the next message to send
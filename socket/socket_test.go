@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+// Two sockets connected over loopback complete the handshake and can talk to each other
+func TestHandshake(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %s", err)
+	}
+	defer listener.Close()
+
+	sockets := make(chan *Socket)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Errorf("Could not accept: %s", err)
+			close(sockets)
+			return
+		}
+		sockets <- NewSocket(conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Could not dial: %s", err)
+	}
+
+	sb := NewSocket(conn)
+	sa := <-sockets
+	if sa == nil {
+		t.FailNow()
+	}
+
+	// Each side sees the other's key, so their fingerprints match
+	if sa.public.Fingerprint() != sb.private.public.Fingerprint() || sb.public.Fingerprint() != sa.private.public.Fingerprint() {
+		t.Errorf("The peer fingerprints do not match the keys")
+	}
+
+	// The connections are left open, recvLoop panics when its own connection is closed
+
+	go func() {
+		sa.send <- []byte("Hello from a")
+	}()
+	if msg := string(<-sb.recv); msg != "Hello from a" {
+		t.Errorf("b received %q", msg)
+	}
+
+	go func() {
+		sb.send <- []byte("Hello from b, this is longer than one AES block")
+	}()
+	if msg := string(<-sa.recv); msg != "Hello from b, this is longer than one AES block" {
+		t.Errorf("a received %q", msg)
+	}
+}